@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"gopkg.in/yaml.v3"
+)
+
+// keyringService is the service attribute the token is stored under in the
+// Secret Service keyring (Linux) or the login keychain (macOS)
+const keyringService = "github-feed"
+
+// tokenProvider is one place a GitHub token can come from. get returns ""
+// without an error when the provider has no token.
+type tokenProvider struct {
+	name string
+	get  func() (token, detail string, err error)
+}
+
+// tokenProviders returns the providers in the order token-source "auto" tries them
+func tokenProviders() []tokenProvider {
+	return []tokenProvider{
+		{"env", envToken},
+		{"command", commandToken},
+		{"keyring", keyringToken},
+		{"gh", ghCLIToken},
+	}
+}
+
+// envToken reads GITHUB_ACTIVITY_TOKEN or GITHUB_TOKEN from the environment or .env
+func envToken() (string, string, error) {
+	if settings != nil {
+		r := settings.values["token"]
+		return r.Value, r.describeSource(settings.Path), nil
+	}
+	for _, name := range settingEnvNames("token") {
+		if value := os.Getenv(name); value != "" {
+			return value, "environment variable " + name, nil
+		}
+	}
+	return "", "", nil
+}
+
+// shellCommand runs command through the platform shell
+func shellCommand(command string) *exec.Cmd {
+	return shellCommandContext(context.Background(), command)
+}
+
+// shellCommandContext is shellCommand, killed when ctx is done
+func shellCommandContext(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// commandToken runs token-command (GITHUB_TOKEN_COMMAND) and uses its output
+func commandToken() (string, string, error) {
+	command := settingValue("token-command")
+	if command == "" {
+		return "", "", nil
+	}
+	cmd := shellCommand(command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("%q failed: %w", command, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", "", fmt.Errorf("%q printed no token", command)
+	}
+	return token, "command " + command, nil
+}
+
+// keyringToken looks the token up with secret-tool on Linux or security on macOS
+func keyringToken() (string, string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-w")
+	default:
+		return "", "", nil
+	}
+
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", "", nil
+	}
+	// Both tools exit non-zero when there is no matching secret
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(string(out)), "keyring service " + keyringService, nil
+}
+
+// ghHostsPath returns the gh CLI's hosts.yml, honoring GH_CONFIG_DIR and XDG_CONFIG_HOME
+func ghHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// ghHost is the github.com entry of gh's hosts.yml
+type ghHost struct {
+	User       string `yaml:"user"`
+	OAuthToken string `yaml:"oauth_token"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// readGHHostsToken returns the github.com token stored in a gh hosts.yml
+func readGHHostsToken(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+	host := hosts["github.com"]
+	if host.OAuthToken != "" {
+		return host.OAuthToken, nil
+	}
+	return host.Users[host.User].OAuthToken, nil
+}
+
+// ghCLIToken reads the gh CLI's token from hosts.yml, or asks "gh auth token"
+// when gh keeps it in the system keyring instead
+func ghCLIToken() (string, string, error) {
+	path := ghHostsPath()
+	token, err := readGHHostsToken(path)
+	if err != nil || token != "" {
+		return token, path, err
+	}
+
+	out, err := exec.Command("gh", "auth", "token", "--hostname", "github.com").Output()
+	if err != nil {
+		return "", "", nil // gh missing or not logged in
+	}
+	return strings.TrimSpace(string(out)), "gh auth token", nil
+}
+
+// resolveToken returns the token from the provider selected by token-source,
+// or from the first provider that has one when it is "auto"
+func resolveToken() (token, detail string, err error) {
+	source := settingValue("token-source")
+	known := source == "auto"
+	for _, p := range tokenProviders() {
+		if source != "auto" && p.name != source {
+			continue
+		}
+		known = true
+		token, detail, err := p.get()
+		if err != nil {
+			return "", "", fmt.Errorf("%s token provider: %w", p.name, err)
+		}
+		if token != "" {
+			return token, detail, nil
+		}
+	}
+	if !known {
+		return "", "", fmt.Errorf("invalid token-source %q (use auto, env, command, keyring or gh)", source)
+	}
+	return "", "", nil
+}
+
+// knownTokenPrefixes are the prefixes of GitHub's token types
+var knownTokenPrefixes = []string{
+	"ghp_",        // classic personal access token
+	"github_pat_", // fine-grained personal access token
+	"gho_",        // OAuth token, as used by the gh CLI
+	"ghu_",        // GitHub App user-to-server token
+	"ghs_",        // GitHub App installation token
+}
+
+// tokenFormatWarning describes a token that doesn't look like any GitHub token, or ""
+func tokenFormatWarning(token string) string {
+	for _, prefix := range knownTokenPrefixes {
+		if strings.HasPrefix(token, prefix) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("GitHub token starts with %q, which is not a known GitHub token prefix (ghp_, github_pat_, gho_, ghu_, ghs_); continuing anyway", token[:min(4, len(token))])
+}
+
+// runAuthCommand implements "github-feed auth status"
+func runAuthCommand(args []string) error {
+	if len(args) != 1 || args[0] != "status" {
+		return errors.New("usage: github-feed auth status")
+	}
+	if _, err := loadCommandSettings(); err != nil {
+		return err
+	}
+
+	app, err := newAppTokenSource()
+	if err != nil {
+		return err
+	}
+	if app != nil {
+		return appAuthStatus(app)
+	}
+
+	token, detail, err := resolveToken()
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("no GitHub token found (checked the environment, .env, token-command, the keyring and the gh CLI)")
+	}
+	fmt.Printf("Token:    %s... from %s\n", token[:min(4, len(token))], detail)
+	if warning := tokenFormatWarning(token); warning != "" {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+
+	ctx := context.Background()
+	client := github.NewClient(nil).WithAuthToken(token)
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		// Installation tokens can't read /user, but can still check their rate limit
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			return fmt.Errorf("token rejected by GitHub: %w", err)
+		}
+		limits, _, limitErr := client.RateLimit.Get(ctx)
+		if limitErr != nil {
+			return fmt.Errorf("token rejected by GitHub: %w", err)
+		}
+		fmt.Println("User:     none (app installation token)")
+		fmt.Printf("Rate:     %d/%d core requests left\n", limits.Core.Remaining, limits.Core.Limit)
+		return nil
+	}
+
+	fmt.Printf("User:     %s\n", user.GetLogin())
+	scopes := resp.Header.Get("X-OAuth-Scopes")
+	if scopes == "" {
+		scopes = "none reported (fine-grained or app token)"
+	}
+	fmt.Printf("Scopes:   %s\n", scopes)
+	fmt.Printf("Rate:     %d/%d core requests left\n", resp.Rate.Remaining, resp.Rate.Limit)
+
+	if username := settingValue("username"); username != "" && !strings.EqualFold(username, user.GetLogin()) {
+		fmt.Printf("Warning: configured username %q does not match the token's user %q\n", username, user.GetLogin())
+	}
+	return nil
+}
+
+// appAuthStatus mints an installation token and reports its expiry and rate limit
+func appAuthStatus(app *appTokenSource) error {
+	ctx := context.Background()
+	if _, err := app.Token(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("App:      %d, installation %d\n", app.appID, app.installationID)
+	fmt.Printf("Token:    ghs_... expires %s\n", app.Expires().Local().Format("2006-01-02 15:04"))
+
+	limits, _, err := app.client().RateLimit.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("installation token rejected by GitHub: %w", err)
+	}
+	fmt.Printf("Rate:     %d/%d core requests left\n", limits.Core.Remaining, limits.Core.Limit)
+	return nil
+}
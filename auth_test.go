@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadGHHostsToken(t *testing.T) {
+	tests := []struct {
+		name, hosts, want string
+	}{
+		{"top-level token", "github.com:\n    user: jane\n    oauth_token: gho_top\n    git_protocol: https\n", "gho_top"},
+		{"per-user token", "github.com:\n    user: jane\n    users:\n        jane:\n            oauth_token: gho_jane\n        bob:\n            oauth_token: gho_bob\n", "gho_jane"},
+		{"keyring only", "github.com:\n    user: jane\n    git_protocol: ssh\n", ""},
+		{"other host", "ghe.example.com:\n    oauth_token: gho_ghe\n", ""},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "hosts.yml")
+		if err := os.WriteFile(path, []byte(tt.hosts), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readGHHostsToken(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: readGHHostsToken() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got, err := readGHHostsToken(filepath.Join(t.TempDir(), "missing.yml")); got != "" || err != nil {
+		t.Errorf("missing hosts.yml = %q, %v, want no token and no error", got, err)
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token command uses sh")
+	}
+	t.Setenv("GITHUB_ACTIVITY_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN_COMMAND", "echo ghs_fromcommand")
+
+	t.Setenv("GITHUB_TOKEN_SOURCE", "auto")
+	token, detail, err := resolveToken()
+	if err != nil || token != "ghs_fromcommand" || !strings.HasPrefix(detail, "command ") {
+		t.Errorf("resolveToken() = %q, %q, %v, want the command's token", token, detail, err)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "ghp_fromenv")
+	if token, _, _ := resolveToken(); token != "ghp_fromenv" {
+		t.Errorf("resolveToken() = %q, want the environment to win in auto mode", token)
+	}
+
+	t.Setenv("GITHUB_TOKEN_SOURCE", "command")
+	if token, _, _ := resolveToken(); token != "ghs_fromcommand" {
+		t.Errorf("resolveToken() with token-source command = %q, want ghs_fromcommand", token)
+	}
+
+	t.Setenv("GITHUB_TOKEN_COMMAND", "exit 3")
+	if _, _, err := resolveToken(); err == nil {
+		t.Error("resolveToken() ignored a failing token command")
+	}
+
+	t.Setenv("GITHUB_TOKEN_SOURCE", "vault")
+	if _, _, err := resolveToken(); err == nil {
+		t.Error("resolveToken() accepted an unknown token-source")
+	}
+}
+
+func TestTokenFormatWarning(t *testing.T) {
+	for _, token := range []string{"ghp_x", "github_pat_x", "gho_x", "ghu_x", "ghs_x"} {
+		if warning := tokenFormatWarning(token); warning != "" {
+			t.Errorf("tokenFormatWarning(%q) = %q, want none", token, warning)
+		}
+	}
+	if warning := tokenFormatWarning("abc123"); !strings.Contains(warning, `"abc1"`) {
+		t.Errorf("tokenFormatWarning(abc123) = %q, want a warning quoting the prefix", warning)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxBatchOps caps how many queued writes are committed in one transaction
+const maxBatchOps = 1000
+
+// writeOp is a single marshaled record waiting to be written
+type writeOp struct {
+	bucket   []byte
+	key      string
+	data     []byte
+	itemType string
+
+	// snapshot, label and at are set for items that record history
+	snapshot map[string]string
+	label    string
+	at       time.Time
+}
+
+// writeBatch feeds queued writes over a channel to a single writer goroutine,
+// which commits them in as few transactions (and fsyncs) as possible
+type writeBatch struct {
+	mu     sync.RWMutex
+	closed bool
+	ops    chan writeOp
+	syncs  chan chan struct{}
+	done   chan struct{}
+
+	debugMode bool
+	written   int
+	failed    int
+}
+
+// enqueue hands op to the writer, returning false if the batch is already flushing
+func (b *writeBatch) enqueue(op writeOp) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+	b.ops <- op
+	return true
+}
+
+// BeginBatch starts queueing writes until FlushBatch is called. Queued writes
+// are committed every maxBatchOps records, so reads during the batch may see
+// any prefix of the cycle's writes. Callers that read back what they wrote
+// call SyncBatch first.
+func (d *Database) BeginBatch(debugMode bool) {
+	if d.batch != nil || d.readOnly {
+		return
+	}
+
+	b := &writeBatch{
+		ops:       make(chan writeOp, 256),
+		syncs:     make(chan chan struct{}),
+		done:      make(chan struct{}),
+		debugMode: debugMode,
+	}
+	d.batch = b
+
+	go func() {
+		defer close(b.done)
+		var pending []writeOp
+		for {
+			select {
+			case op, ok := <-b.ops:
+				if !ok {
+					if len(pending) > 0 {
+						d.commitBatch(b, pending)
+					}
+					return
+				}
+				pending = append(pending, op)
+				if len(pending) >= maxBatchOps {
+					d.commitBatch(b, pending)
+					pending = nil
+				}
+			case ack := <-b.syncs:
+				// Everything queued before SyncBatch was called is in the buffer
+				for len(b.ops) > 0 {
+					pending = append(pending, <-b.ops)
+				}
+				if len(pending) > 0 {
+					d.commitBatch(b, pending)
+					pending = nil
+				}
+				close(ack)
+			}
+		}
+	}()
+}
+
+// SyncBatch commits the writes queued so far without ending the batch, so
+// they are visible to reads that follow
+func (d *Database) SyncBatch() {
+	b := d.batch
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	ack := make(chan struct{})
+	b.syncs <- ack
+	<-ack
+}
+
+// commitBatch writes ops in a single transaction. A failing record is counted
+// and skipped; a failing commit counts every record in the transaction.
+func (d *Database) commitBatch(b *writeBatch, ops []writeOp) {
+	written, failed := 0, 0
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		written, failed = 0, 0
+		for _, op := range ops {
+			if err := d.applyWrite(tx, op, b.debugMode); err != nil {
+				failed++
+				if b.debugMode {
+					fmt.Printf("  [DB] Error saving %s %s: %v\n", op.itemType, op.key, err)
+				}
+				continue
+			}
+			written++
+		}
+		return nil
+	})
+
+	if err != nil {
+		if b.debugMode {
+			fmt.Printf("  [DB] Error committing batch of %d writes: %v\n", len(ops), err)
+		}
+		b.failed += len(ops)
+		return
+	}
+
+	b.written += written
+	b.failed += failed
+	if b.debugMode {
+		fmt.Printf("  [DB] Committed batch: %d written, %d failed\n", written, failed)
+	}
+}
+
+// FlushBatch commits all queued writes and stops batching, returning how many
+// records were written and how many failed
+func (d *Database) FlushBatch() (written, failed int) {
+	b := d.batch
+	if b == nil {
+		return 0, 0
+	}
+
+	b.mu.Lock()
+	b.closed = true
+	close(b.ops)
+	b.mu.Unlock()
+
+	<-b.done
+	d.batch = nil
+	return b.written, b.failed
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestBatchWrites(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	db.BeginBatch(false)
+	pr := &github.PullRequest{Number: github.Int(1), Title: github.String("first")}
+	if err := db.SavePullRequestWithLabel("owner", "repo", pr, "Mentioned", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+
+	if _, err := db.GetPullRequest("owner", "repo", 1); err == nil {
+		t.Errorf("queued write should not be visible before FlushBatch")
+	}
+
+	pr.Title = github.String("second")
+	if err := db.SavePullRequestWithLabel("owner", "repo", pr, "Authored", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+
+	// SyncBatch makes queued writes readable while the batch stays open
+	db.SyncBatch()
+	if got, err := db.GetPullRequest("owner", "repo", 1); err != nil || got.GetTitle() != "second" {
+		t.Errorf("GetPullRequest() after SyncBatch = %v, %v, want the second write", got, err)
+	}
+	pr.Title = github.String("third")
+	if err := db.SavePullRequestWithLabel("owner", "repo", pr, "Authored", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+
+	written, failed := db.FlushBatch()
+	if written != 3 || failed != 0 {
+		t.Errorf("FlushBatch() = %d written, %d failed, want 3, 0", written, failed)
+	}
+
+	got, label, err := db.GetPullRequestWithLabel("owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("GetPullRequestWithLabel() error = %v", err)
+	}
+	if got.GetTitle() != "third" || label != "Authored" {
+		t.Errorf("got %q/%q, want the last queued write third/Authored", got.GetTitle(), label)
+	}
+
+	history, err := db.GetHistory("owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(history) != 3 || !history[0].FirstSeen {
+		t.Errorf("GetHistory() = %+v, want first-seen entry followed by the two title changes", history)
+	}
+}
+
+func benchmarkSaves(b *testing.B, batched bool) {
+	const items = 500
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		db, err := OpenDatabase(filepath.Join(b.TempDir(), "bench.db"))
+		if err != nil {
+			b.Fatalf("OpenDatabase() error = %v", err)
+		}
+		b.StartTimer()
+
+		if batched {
+			db.BeginBatch(false)
+		}
+
+		var wg sync.WaitGroup
+		for n := 1; n <= items; n++ {
+			wg.Go(func() {
+				pr := &github.PullRequest{
+					Number: github.Int(n),
+					Title:  github.String(fmt.Sprintf("PR %d", n)),
+				}
+				if err := db.SavePullRequestWithLabel("owner", "repo", pr, "Authored", false); err != nil {
+					b.Errorf("SavePullRequestWithLabel() error = %v", err)
+				}
+				comment := &github.PullRequestComment{ID: github.Int64(int64(n))}
+				if err := db.SavePRComment("owner", "repo", n, comment, false); err != nil {
+					b.Errorf("SavePRComment() error = %v", err)
+				}
+			})
+		}
+		wg.Wait()
+
+		if batched {
+			if _, failed := db.FlushBatch(); failed > 0 {
+				b.Errorf("FlushBatch() failed %d writes", failed)
+			}
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkSaveUnbatched(b *testing.B) { benchmarkSaves(b, false) }
+func BenchmarkSaveBatched(b *testing.B)   { benchmarkSaves(b, true) }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subcommand is a named command run as "github-feed <name> [arguments]"
+type subcommand struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+// subcommands returns all registered subcommands in the order they are listed in usage output
+func subcommands() []subcommand {
+	return []subcommand{
+		{"history", "history owner/repo#N       Show the timeline of field changes for a PR or issue", runHistoryCommand},
+		{"db", "db doctor [--quarantine]   Check the database for unreadable or orphaned records", runDBCommand},
+		{"rss", "rss [--out FILE]           Write cached PR/issue updates as an RSS 2.0 feed", func(args []string) error { return runFeedCommand("rss", args) }},
+		{"atom", "atom [--out FILE]          Write cached PR/issue updates as an Atom feed", func(args []string) error { return runFeedCommand("atom", args) }},
+		{"serve", "serve [--addr :8080]       Serve a JSON API, Atom feed and dashboard, fetching in the background", runServeCommand},
+		{"webhook", "webhook [--listen :9000]   Receive GitHub webhooks and update the cache as events arrive", runWebhookCommand},
+		{"hooks", "hooks list                 List notification hooks (also: hooks test NAME owner/repo#N)", runHooksCommand},
+		{"metrics", "metrics [--textfile FILE]  Fetch and print Prometheus metrics, or write a node_exporter .prom file", runMetricsCommand},
+		{"digest", "digest --to ADDR           Email the feed since the last digest over SMTP, or write a .eml", runDigestCommand},
+		{"stats", "stats reviews [--time 3m]   Review turnaround per repo and reviewer (p50/p90, --format table|json)", runStatsCommand},
+		{"view", "view NAME [flags]          Show a saved view (also: view save NAME [flags], view list)", runViewCommand},
+		{"auth", "auth status                Check the GitHub token, where it came from and its scopes", runAuthCommand},
+		{"config", "config show                Print the effective configuration and where each value comes from", runConfigCommand},
+	}
+}
+
+func findSubcommand(name string) (subcommand, bool) {
+	for _, cmd := range subcommands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return subcommand{}, false
+}
+
+func printSubcommands() {
+	for _, cmd := range subcommands() {
+		fmt.Fprintf(os.Stderr, "  %s\n", cmd.usage)
+	}
+}
+
+func cacheDatabasePath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "github.db"), nil
+}
+
+// openCacheDatabase opens the database in the config directory for subcommands that write
+func openCacheDatabase() (*Database, error) {
+	path, err := cacheDatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenDatabaseWait(path, 10*time.Second, false)
+}
+
+// openCacheDatabaseReadOnly opens the database for subcommands that only query it
+func openCacheDatabaseReadOnly() (*Database, error) {
+	path, err := cacheDatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenDatabaseReadOnly(path, false)
+}
+
+// parseItemRef parses an "owner/repo#N" reference as produced by buildItemKey
+func parseItemRef(ref string) (owner, repo string, number int, err error) {
+	slash := strings.Index(ref, "/")
+	hash := strings.LastIndex(ref, "#")
+	if slash <= 0 || hash <= slash+1 || hash == len(ref)-1 {
+		return "", "", 0, fmt.Errorf("invalid item reference %q (expected owner/repo#N)", ref)
+	}
+
+	number, err = strconv.Atoi(ref[hash+1:])
+	if err != nil || number < 1 {
+		return "", "", 0, fmt.Errorf("invalid item number in %q (expected owner/repo#N)", ref)
+	}
+	return ref[:slash], ref[slash+1 : hash], number, nil
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v57/github"
@@ -15,10 +18,30 @@ var (
 	pullRequestsBucket = []byte("pull_requests")
 	issuesBucket       = []byte("issues")
 	commentsBucket     = []byte("comments")
+	historyBucket      = []byte("history")
+	corruptBucket      = []byte("corrupt")
+
+	allBuckets = [][]byte{
+		pullRequestsBucket, issuesBucket, commentsBucket, historyBucket, corruptBucket,
+		updatedIndexBucket, repoIndexBucket, metaBucket, updatesBucket, hooksFiredBucket,
+		timelinesBucket,
+	}
 )
 
 type Database struct {
-	db *bolt.DB
+	db       *bolt.DB
+	path     string
+	readOnly bool
+
+	// snapshotPath is the temporary copy a read-only instance got from the lock holder
+	snapshotPath string
+	lockServer   *http.Server
+
+	// batch queues writes while a fetch cycle is running, see BeginBatch
+	batch *writeBatch
+
+	// skipped holds "bucket/key" of records the getters could not decode
+	skipped sync.Map
 }
 
 // buildItemKey creates a consistent key format for PRs and issues
@@ -41,25 +64,115 @@ func (d *Database) save(bucket []byte, key string, data interface{}, debugMode b
 		return fmt.Errorf("failed to marshal %s: %w", itemType, err)
 	}
 
-	err = d.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(bucket)
-		return b.Put([]byte(key), jsonData)
+	return d.write(writeOp{bucket: bucket, key: key, data: jsonData, itemType: itemType}, debugMode)
+}
+
+// saveWithHistory saves an item like save, and in the same transaction appends a
+// history entry with the fields that differ from the previously stored record
+func (d *Database) saveWithHistory(bucket []byte, key string, data interface{}, snapshot map[string]string, label string, debugMode bool, itemType string) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		if debugMode {
+			fmt.Printf("  [DB] Error marshaling %s %s: %v\n", itemType, key, err)
+		}
+		return fmt.Errorf("failed to marshal %s: %w", itemType, err)
+	}
+
+	return d.write(writeOp{
+		bucket:   bucket,
+		key:      key,
+		data:     jsonData,
+		itemType: itemType,
+		snapshot: snapshot,
+		label:    label,
+		at:       time.Now(),
+	}, debugMode)
+}
+
+// write applies a single write in its own transaction, or queues it when a
+// write batch is active
+func (d *Database) write(op writeOp, debugMode bool) error {
+	if d.batch != nil && d.batch.enqueue(op) {
+		if debugMode {
+			fmt.Printf("  [DB] Queued %s %s\n", op.itemType, op.key)
+		}
+		return nil
+	}
+
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		return d.applyWrite(tx, op, debugMode)
 	})
 
 	if err != nil {
 		if debugMode {
-			fmt.Printf("  [DB] Error saving %s %s: %v\n", itemType, key, err)
+			fmt.Printf("  [DB] Error saving %s %s: %v\n", op.itemType, op.key, err)
 		}
 	} else if debugMode {
-		fmt.Printf("  [DB] Saved %s %s\n", itemType, key)
+		fmt.Printf("  [DB] Saved %s %s\n", op.itemType, op.key)
 	}
 
 	return err
 }
 
+// applyWrite puts op into tx. Items also get their history entry and index
+// entries updated in the same transaction.
+func (d *Database) applyWrite(tx *bolt.Tx, op writeOp, debugMode bool) error {
+	b := tx.Bucket(op.bucket)
+
+	kind := itemKind(op.bucket)
+	if kind == "" {
+		return b.Put([]byte(op.key), op.data)
+	}
+
+	var previous map[string]string
+	if old := b.Get([]byte(op.key)); old != nil {
+		// An unreadable old record is treated as first seen rather than failing the save
+		previous, _ = snapshotFromRecord(kind, old)
+	}
+
+	current := op.snapshot
+	if current == nil {
+		var err error
+		if current, err = snapshotFromRecord(kind, op.data); err != nil {
+			return err
+		}
+	}
+
+	if op.snapshot != nil {
+		if changes := diffSnapshots(previous, op.snapshot); len(changes) > 0 {
+			entry, err := json.Marshal(HistoryEntry{
+				Timestamp: op.at,
+				Kind:      kind,
+				Label:     op.label,
+				FirstSeen: previous == nil,
+				Changes:   changes,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to marshal history entry: %w", err)
+			}
+			if err := tx.Bucket(historyBucket).Put([]byte(buildHistoryKey(op.key, op.at)), entry); err != nil {
+				return err
+			}
+			if debugMode {
+				fmt.Printf("  [DB] Recorded %d changed field(s) for %s %s\n", len(changes), op.itemType, op.key)
+			}
+		}
+	}
+
+	if err := indexItem(tx, op.bucket, op.key, previous, current); err != nil {
+		return fmt.Errorf("failed to index %s: %w", op.key, err)
+	}
+
+	return b.Put([]byte(op.key), op.data)
+}
+
 func OpenDatabase(path string) (*Database, error) {
 	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			holder, _ := readLockInfo(path)
+			return nil, &ErrDatabaseLocked{Holder: holder}
+		}
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
@@ -69,8 +182,7 @@ func OpenDatabase(path string) (*Database, error) {
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		buckets := [][]byte{pullRequestsBucket, issuesBucket, commentsBucket}
-		for _, bucket := range buckets {
+		for _, bucket := range allBuckets {
 			_, err := tx.CreateBucketIfNotExists(bucket)
 			if err != nil {
 				return fmt.Errorf("failed to create bucket %s: %w", string(bucket), err)
@@ -84,11 +196,40 @@ func OpenDatabase(path string) (*Database, error) {
 		return nil, err
 	}
 
-	return &Database{db: db}, nil
+	d := &Database{db: db, path: path}
+	if err := d.ensureIndexes(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
 }
 
 func (d *Database) Close() error {
-	return d.db.Close()
+	d.stopLockHolder()
+	err := d.db.Close()
+	if d.snapshotPath != "" {
+		os.Remove(d.snapshotPath)
+	}
+	return err
+}
+
+// skipRecord notes an unreadable record so views can continue without it
+func (d *Database) skipRecord(bucket []byte, key string, err error, debugMode bool) {
+	if debugMode {
+		fmt.Printf("  [DB] Skipping unreadable record %s/%s: %v\n", string(bucket), key, err)
+	}
+	d.skipped.Store(string(bucket)+"/"+key, struct{}{})
+}
+
+// SkippedRecordCount returns how many distinct records the getters had to skip
+func (d *Database) SkippedRecordCount() int {
+	count := 0
+	d.skipped.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
 }
 
 type PRWithLabel struct {
@@ -107,7 +248,7 @@ func (d *Database) SavePullRequestWithLabel(owner, repo string, pr *github.PullR
 		PR:    pr,
 		Label: label,
 	}
-	return d.save(pullRequestsBucket, key, prWithLabel, debugMode, fmt.Sprintf("PR with label %s", label))
+	return d.saveWithHistory(pullRequestsBucket, key, prWithLabel, prSnapshot(pr), label, debugMode, fmt.Sprintf("PR with label %s", label))
 }
 
 func (d *Database) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
@@ -187,7 +328,7 @@ func (d *Database) SaveIssueWithLabel(owner, repo string, issue *github.Issue, l
 		Issue: issue,
 		Label: label,
 	}
-	return d.save(issuesBucket, key, issueWithLabel, debugMode, fmt.Sprintf("issue with label %s", label))
+	return d.saveWithHistory(issuesBucket, key, issueWithLabel, issueSnapshot(issue), label, debugMode, fmt.Sprintf("issue with label %s", label))
 }
 
 func (d *Database) GetIssue(owner, repo string, number int) (*github.Issue, error) {
@@ -259,10 +400,7 @@ func (d *Database) SaveComment(owner, repo string, itemNumber int, comment *gith
 		return fmt.Errorf("failed to marshal comment: %w", err)
 	}
 
-	return d.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(commentsBucket)
-		return b.Put([]byte(key), data)
-	})
+	return d.write(writeOp{bucket: commentsBucket, key: key, data: data, itemType: "comment"}, false)
 }
 
 func (d *Database) SavePRComment(owner, repo string, prNumber int, comment *github.PullRequestComment, debugMode bool) error {
@@ -276,20 +414,7 @@ func (d *Database) SavePRComment(owner, repo string, prNumber int, comment *gith
 		return fmt.Errorf("failed to marshal PR comment: %w", err)
 	}
 
-	err = d.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(commentsBucket)
-		return b.Put([]byte(key), data)
-	})
-
-	if err != nil {
-		if debugMode {
-			fmt.Printf("  [DB] Error saving PR comment %s: %v\n", key, err)
-		}
-	} else if debugMode {
-		fmt.Printf("  [DB] Saved PR comment %s\n", key)
-	}
-
-	return err
+	return d.write(writeOp{bucket: commentsBucket, key: key, data: data, itemType: "PR comment"}, debugMode)
 }
 
 func (d *Database) GetComment(owner, repo string, itemNumber int, commentType string, commentID int64) (*github.IssueComment, error) {
@@ -339,10 +464,8 @@ func (d *Database) GetAllPullRequests(debugMode bool) (map[string]*github.PullRe
 
 			var pr github.PullRequest
 			if err := json.Unmarshal(v, &pr); err != nil {
-				if debugMode {
-					fmt.Printf("  [DB] Error unmarshaling PR %s: %v\n", string(k), err)
-				}
-				return err
+				d.skipRecord(pullRequestsBucket, string(k), err, debugMode)
+				return nil
 			}
 			prs[string(k)] = &pr
 			return nil
@@ -385,10 +508,8 @@ func (d *Database) GetAllPullRequestsWithLabels(debugMode bool) (map[string]*git
 
 			var pr github.PullRequest
 			if err := json.Unmarshal(v, &pr); err != nil {
-				if debugMode {
-					fmt.Printf("  [DB] Error unmarshaling PR %s: %v\n", key, err)
-				}
-				return err
+				d.skipRecord(pullRequestsBucket, key, err, debugMode)
+				return nil
 			}
 			prs[key] = &pr
 			labels[key] = "" // No label in old format
@@ -428,10 +549,8 @@ func (d *Database) GetAllIssues(debugMode bool) (map[string]*github.Issue, error
 
 			var issue github.Issue
 			if err := json.Unmarshal(v, &issue); err != nil {
-				if debugMode {
-					fmt.Printf("  [DB] Error unmarshaling issue %s: %v\n", string(k), err)
-				}
-				return err
+				d.skipRecord(issuesBucket, string(k), err, debugMode)
+				return nil
 			}
 			issues[string(k)] = &issue
 			return nil
@@ -474,10 +593,8 @@ func (d *Database) GetAllIssuesWithLabels(debugMode bool) (map[string]*github.Is
 
 			var issue github.Issue
 			if err := json.Unmarshal(v, &issue); err != nil {
-				if debugMode {
-					fmt.Printf("  [DB] Error unmarshaling issue %s: %v\n", key, err)
-				}
-				return err
+				d.skipRecord(issuesBucket, key, err, debugMode)
+				return nil
 			}
 			issues[key] = &issue
 			labels[key] = "" // No label in old format
@@ -527,7 +644,8 @@ func (d *Database) GetPRComments(owner, repo string, prNumber int) ([]*github.Pu
 		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
 			var comment github.PullRequestComment
 			if err := json.Unmarshal(v, &comment); err != nil {
-				return err
+				d.skipRecord(commentsBucket, string(k), err, false)
+				continue
 			}
 			comments = append(comments, &comment)
 		}
@@ -539,3 +657,52 @@ func (d *Database) GetPRComments(owner, repo string, prNumber int) ([]*github.Pu
 	}
 	return comments, nil
 }
+
+// GetIssueComments returns the cached conversation comments of an issue or PR
+func (d *Database) GetIssueComments(owner, repo string, number int) ([]*github.IssueComment, error) {
+	var comments []*github.IssueComment
+	prefix := fmt.Sprintf("%s/%s#%d/issue_comment/", owner, repo, number)
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(commentsBucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			var comment github.IssueComment
+			if err := json.Unmarshal(v, &comment); err != nil {
+				d.skipRecord(commentsBucket, string(k), err, false)
+				continue
+			}
+			comments = append(comments, &comment)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetHistory returns the recorded history entries for an item, oldest first
+func (d *Database) GetHistory(owner, repo string, number int) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	prefix := buildItemKey(owner, repo, number) + "@"
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket)
+		c := b.Cursor()
+
+		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
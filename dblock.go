@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// LockInfo describes the process holding the database write lock. It is stored
+// next to the database as "<db>.lock" while a writer has the database open.
+type LockInfo struct {
+	PID     int       `json:"pid"`
+	Addr    string    `json:"addr"`
+	Token   string    `json:"token"`
+	Started time.Time `json:"started"`
+}
+
+// ErrDatabaseLocked is returned when another process holds the database write lock
+type ErrDatabaseLocked struct {
+	Holder *LockInfo
+}
+
+func (e *ErrDatabaseLocked) Error() string {
+	if e.Holder == nil {
+		return "database is locked by another github-feed process"
+	}
+	return fmt.Sprintf("database is locked by another github-feed process (PID %d, running since %s)",
+		e.Holder.PID, e.Holder.Started.Local().Format("15:04:05"))
+}
+
+func lockInfoPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// readLockInfo returns the lock holder recorded next to the database, if any
+func readLockInfo(dbPath string) (*LockInfo, error) {
+	data, err := os.ReadFile(lockInfoPath(dbPath))
+	if err != nil {
+		return nil, err
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// startLockHolder records this process as the lock holder and serves consistent
+// snapshots of the database to read-only instances on a loopback port
+func (d *Database) startLockHolder() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen for snapshot requests: %w", err)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	info := LockInfo{
+		PID:     os.Getpid(),
+		Addr:    listener.Addr().String(),
+		Token:   hex.EncodeToString(tokenBytes),
+		Started: time.Now(),
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	if err := os.WriteFile(lockInfoPath(d.path), data, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Lock-Token") != info.Token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		err := d.db.View(func(tx *bolt.Tx) error {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.FormatInt(tx.Size(), 10))
+			_, err := tx.WriteTo(w)
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	d.lockServer = &http.Server{Handler: mux}
+	go d.lockServer.Serve(listener)
+	return nil
+}
+
+// holdLock makes a long-lived writer the lock holder, so other instances can
+// name it and read snapshots while it runs. Short-lived writers skip this and
+// other instances simply wait for them.
+func (d *Database) holdLock() {
+	if d.readOnly || d.lockServer != nil {
+		return
+	}
+	if err := d.startLockHolder(); err != nil {
+		// Other instances fall back to a plain lock timeout without the lock file
+		fmt.Fprintf(config.statusOut, "Warning: %v\n", err)
+	}
+}
+
+// stopLockHolder stops serving snapshots and removes the lock file
+func (d *Database) stopLockHolder() {
+	if d.lockServer == nil {
+		return
+	}
+	d.lockServer.Close()
+	d.lockServer = nil
+	if info, err := readLockInfo(d.path); err == nil && info.PID == os.Getpid() {
+		os.Remove(lockInfoPath(d.path))
+	}
+}
+
+// fetchSnapshot asks the lock holder for a consistent copy of the database and
+// writes it to a temporary file, returning its path
+func fetchSnapshot(info *LockInfo) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://"+info.Addr+"/snapshot", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Lock-Token", info.Token)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("lock holder (PID %d) is not reachable: %w", info.PID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lock holder (PID %d) refused snapshot: %s", info.PID, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp("", "github-feed-snapshot-*.db")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// OpenDatabaseWait opens the database for writing, queueing behind another
+// writer for up to wait before giving up with an ErrDatabaseLocked
+func OpenDatabaseWait(path string, wait time.Duration, debugMode bool) (*Database, error) {
+	deadline := time.Now().Add(wait)
+	announced := false
+
+	for {
+		db, err := OpenDatabase(path)
+		var locked *ErrDatabaseLocked
+		if err == nil || !errors.As(err, &locked) || time.Now().After(deadline) {
+			return db, err
+		}
+
+		if !announced || debugMode {
+			fmt.Fprintf(config.statusOut, "%v, waiting up to %v for it to finish...\n", locked, time.Until(deadline).Round(time.Second))
+			announced = true
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// OpenDatabaseReadOnly opens the database without taking the write lock. When
+// another process is writing, a snapshot is fetched from it instead, so read-only
+// commands and --local can run alongside a fetch or watch loop.
+func OpenDatabaseReadOnly(path string, debugMode bool) (*Database, error) {
+	if _, err := os.Stat(path); err == nil {
+		db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 200 * time.Millisecond, ReadOnly: true})
+		if err == nil {
+			d := &Database{db: db, path: path, readOnly: true}
+			if d.hasAllBuckets() {
+				return d, nil
+			}
+			// Databases written by older versions may lack newer buckets, create them once
+			db.Close()
+		} else if !errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		} else {
+			return openSnapshot(path, debugMode)
+		}
+	}
+
+	rw, err := OpenDatabase(path)
+	if err != nil {
+		var locked *ErrDatabaseLocked
+		if errors.As(err, &locked) {
+			return openSnapshot(path, debugMode)
+		}
+		return nil, err
+	}
+	rw.Close()
+	return OpenDatabaseReadOnly(path, debugMode)
+}
+
+// openSnapshot opens a snapshot of the database served by the current lock holder
+func openSnapshot(path string, debugMode bool) (*Database, error) {
+	info, err := readLockInfo(path)
+	if err != nil {
+		return nil, &ErrDatabaseLocked{}
+	}
+
+	if debugMode {
+		fmt.Printf("  [DB] Database is locked by PID %d, reading a snapshot from it\n", info.PID)
+	}
+
+	snapshotPath, err := fetchSnapshot(info)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", &ErrDatabaseLocked{Holder: info}, err)
+	}
+
+	db, err := bolt.Open(snapshotPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		os.Remove(snapshotPath)
+		return nil, fmt.Errorf("failed to open database snapshot: %w", err)
+	}
+	return &Database{db: db, path: path, readOnly: true, snapshotPath: snapshotPath}, nil
+}
+
+func (d *Database) hasAllBuckets() bool {
+	missing := false
+	d.db.View(func(tx *bolt.Tx) error {
+		for _, bucket := range allBuckets {
+			if tx.Bucket(bucket) == nil {
+				missing = true
+			}
+		}
+		return nil
+	})
+	return !missing
+}
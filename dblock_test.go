@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestReadOnlyOpenAlongsideWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	writer, err := OpenDatabase(path)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer writer.Close()
+	if _, err := os.Stat(lockInfoPath(path)); !os.IsNotExist(err) {
+		t.Errorf("OpenDatabase() wrote a lock file before holdLock(): %v", err)
+	}
+	writer.holdLock()
+
+	pr := &github.PullRequest{Number: github.Int(7), Title: github.String("snapshot me")}
+	if err := writer.SavePullRequestWithLabel("owner", "repo", pr, "Authored", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+
+	_, err = OpenDatabase(path)
+	var locked *ErrDatabaseLocked
+	if !errors.As(err, &locked) {
+		t.Fatalf("second OpenDatabase() error = %v, want ErrDatabaseLocked", err)
+	}
+	if locked.Holder == nil || locked.Holder.PID != os.Getpid() {
+		t.Errorf("ErrDatabaseLocked holder = %+v, want PID %d", locked.Holder, os.Getpid())
+	}
+
+	reader, err := OpenDatabaseReadOnly(path, false)
+	if err != nil {
+		t.Fatalf("OpenDatabaseReadOnly() error = %v", err)
+	}
+	got, label, err := reader.GetPullRequestWithLabel("owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("GetPullRequestWithLabel() from snapshot error = %v", err)
+	}
+	if got.GetTitle() != "snapshot me" || label != "Authored" {
+		t.Errorf("snapshot returned %q/%q, want %q/%q", got.GetTitle(), label, "snapshot me", "Authored")
+	}
+
+	snapshot := reader.snapshotPath
+	reader.Close()
+	if _, err := os.Stat(snapshot); !os.IsNotExist(err) {
+		t.Errorf("snapshot file %s was not removed on Close", snapshot)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// lastDigestKey in the meta bucket holds when the last digest was sent
+var lastDigestKey = []byte("last_digest")
+
+// smtpTimeout bounds connecting to the SMTP server
+const smtpTimeout = 30 * time.Second
+
+// LastDigest returns when the last digest was sent, or the zero time
+func (d *Database) LastDigest() (time.Time, error) {
+	var last time.Time
+	err := d.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(metaBucket).Get(lastDigestKey)
+		if value == nil {
+			return nil
+		}
+		var err error
+		last, err = time.Parse(time.RFC3339, string(value))
+		return err
+	})
+	return last, err
+}
+
+// SetLastDigest records when a digest was sent
+func (d *Database) SetLastDigest(t time.Time) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(lastDigestKey, []byte(t.UTC().Format(time.RFC3339)))
+	})
+}
+
+// digestSubject summarizes the feed for the subject line
+func digestSubject(sections FeedSections, since time.Time) string {
+	prs, issues := 0, 0
+	for _, e := range hookEvents(sections) {
+		if e.Kind == "pr" {
+			prs++
+		} else {
+			issues++
+		}
+	}
+	return fmt.Sprintf("GitHub feed: %d PRs, %d issues since %s", prs, issues, since.Local().Format("Jan 2 15:04"))
+}
+
+// writeQuotedPrintablePart adds a quoted-printable part rendered by render
+func writeQuotedPrintablePart(w *multipart.Writer, contentType string, render func(*bytes.Buffer) error) error {
+	var content bytes.Buffer
+	if err := render(&content); err != nil {
+		return err
+	}
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(content.Bytes()); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// buildDigestMessage renders the feed as a multipart/alternative email with a
+// markdown text part and the HTML report
+func buildDigestMessage(from string, to []string, sections FeedSections, since, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	err := writeQuotedPrintablePart(parts, "text/plain", func(b *bytes.Buffer) error {
+		return renderMarkdown(b, sections)
+	})
+	if err == nil {
+		footer := "Activity since " + since.Local().Format("2006-01-02 15:04") + ", sent by github-feed digest"
+		err = writeQuotedPrintablePart(parts, "text/html", func(b *bytes.Buffer) error {
+			return renderHTMLPage(b, sections, 0, footer)
+		})
+	}
+	if err == nil {
+		err = parts.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	domain := "github-feed"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	var msg bytes.Buffer
+	header := func(name, value string) { fmt.Fprintf(&msg, "%s: %s\r\n", name, value) }
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", digestSubject(sections, since)))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%d.%s@%s>", now.Unix(), hex.EncodeToString(id), domain))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// smtpOptions configures how a digest is sent
+type smtpOptions struct {
+	addr        string
+	user        string
+	password    string
+	startTLS    bool
+	implicitTLS bool
+}
+
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// sendDigest delivers msg over SMTP, upgrading with STARTTLS when the server
+// offers it. Only localhost servers may be used without TLS.
+func sendDigest(opts smtpOptions, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(opts.addr)
+	if err != nil {
+		return fmt.Errorf("invalid --smtp %q (use host:port): %w", opts.addr, err)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	if opts.implicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", opts.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", opts.addr)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if !opts.implicitTLS && opts.startTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS: %w", err)
+			}
+		} else if !isLocalhost(host) {
+			return fmt.Errorf("%s does not offer STARTTLS (use --tls, or --starttls=false to send unencrypted)", host)
+		}
+	}
+	if opts.user != "" {
+		if err := c.Auth(smtp.PlainAuth("", opts.user, opts.password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication: %w", err)
+		}
+	}
+
+	if err := c.Mail(addressOnly(from)); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(addressOnly(rcpt)); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// addressOnly strips the display name from "Name <addr>"
+func addressOnly(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
+
+// runDigestCommand implements "github-feed digest"
+func runDigestCommand(args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	opts := registerFlags(fs)
+	smtpAddr := fs.String("smtp", "", "SMTP server as host:port")
+	smtpUser := fs.String("smtp-user", "", "SMTP username (enables authentication)")
+	smtpPassword := fs.String("smtp-password", "", "SMTP password (default SMTP_PASSWORD)")
+	startTLS := fs.Bool("starttls", true, "Upgrade the connection with STARTTLS")
+	implicitTLS := fs.Bool("tls", false, "Connect with TLS from the start (usually port 465)")
+	to := fs.String("to", "", "Comma-separated recipients (default digest-to)")
+	from := fs.String("from", "", "Sender address (default digest-from, or the first recipient)")
+	dryRun := fs.Bool("dry-run", false, "Write the message to --out (default digest-DATE.eml) instead of sending it")
+	fs.Parse(args)
+
+	// An explicit range overrides "since the last digest"
+	explicitWindow := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "time" || f.Name == "since" || f.Name == "until" {
+			explicitWindow = true
+		}
+	})
+	// setupFeed would write the report to --out; here it names the .eml
+	emlPath := opts.outputPath
+	opts.outputPath = ""
+
+	db := setupFeed(fs, opts)
+	if db != nil {
+		defer db.Close()
+	}
+
+	if *to == "" {
+		*to = settingValue("digest-to")
+	}
+	if *from == "" {
+		*from = settingValue("digest-from")
+	}
+	if *smtpPassword == "" {
+		*smtpPassword = settingValue("smtp-password")
+	}
+	recipients := splitList(*to)
+	if len(recipients) == 0 {
+		return errors.New("no recipients (use --to or digest-to in config.yaml)")
+	}
+	if *from == "" {
+		*from = recipients[0]
+	}
+	if *smtpAddr == "" && !*dryRun {
+		return errors.New("no SMTP server (use --smtp host:port, or --dry-run to write the message to a file)")
+	}
+
+	now := time.Now()
+	if !explicitWindow {
+		var last time.Time
+		if db != nil {
+			var err error
+			if last, err = db.LastDigest(); err != nil {
+				return fmt.Errorf("reading the last digest time: %w", err)
+			}
+		}
+		if last.IsZero() {
+			last = now.Add(-24 * time.Hour)
+		}
+		config.window = TimeWindow{Since: last}
+	}
+
+	sections, ok := fetchActivity()
+	if !ok {
+		return errors.New("digest skipped because of the rate limit")
+	}
+	if sections.isEmpty() {
+		fmt.Printf("No activity %s; no digest sent\n", config.window)
+		return nil
+	}
+
+	msg, err := buildDigestMessage(*from, recipients, sections, config.window.Since, now)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if emlPath == "" {
+			emlPath = "digest-" + now.Format("2006-01-02") + ".eml"
+		}
+		if err := os.WriteFile(emlPath, msg, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote the digest to %s (not sent, last digest time unchanged)\n", emlPath)
+		return nil
+	}
+
+	smtpOpts := smtpOptions{addr: *smtpAddr, user: *smtpUser, password: *smtpPassword, startTLS: *startTLS, implicitTLS: *implicitTLS}
+	if err := sendDigest(smtpOpts, *from, recipients, msg); err != nil {
+		return fmt.Errorf("sending the digest: %w", err)
+	}
+	fmt.Printf("Sent the digest to %s\n", strings.Join(recipients, ", "))
+
+	if db == nil || db.readOnly {
+		fmt.Println("Warning: the database is read-only, so the next digest will start from the same time")
+		return nil
+	}
+	return db.SetLastDigest(now)
+}
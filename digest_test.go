@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func testDigestSections() FeedSections {
+	updated := &github.Timestamp{Time: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)}
+	issue := IssueActivity{Label: "Mentioned", Owner: "acme", Repo: "app", Issue: &github.Issue{
+		Number: github.Int(4), Title: github.String("Crash on start"), State: github.String("open"),
+		HTMLURL: github.String("https://github.com/acme/app/issues/4"), UpdatedAt: updated, User: &github.User{Login: github.String("carol")},
+	}}
+	return FeedSections{OpenPRs: []PRActivity{{
+		Label: "Review Requested", Owner: "acme", Repo: "app", HasUpdates: true,
+		PR: &github.PullRequest{
+			Number: github.Int(5), Title: github.String("Fix crash – ünïcode"), State: github.String("open"),
+			HTMLURL: github.String("https://github.com/acme/app/pull/5"), UpdatedAt: updated, User: &github.User{Login: github.String("bob")},
+		},
+		Issues: []IssueActivity{issue},
+	}}}
+}
+
+func TestBuildDigestMessage(t *testing.T) {
+	since := time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)
+	now := since.Add(24 * time.Hour)
+	raw, err := buildDigestMessage("Feed <feed@example.com>", []string{"me@example.com"}, testDigestSections(), since, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || !strings.HasPrefix(subject, "GitHub feed: 1 PRs, 1 issues since") {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if msg.Header.Get("To") != "me@example.com" || !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("headers = %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	bodies := make(map[string]string)
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[mediaType] = string(decoded)
+	}
+
+	text := bodies["text/plain"]
+	if !strings.Contains(text, "[acme/app\\#5](https://github.com/acme/app/pull/5)") || !strings.Contains(text, "  - **MENTIONED**") {
+		t.Errorf("text part missing the PR or its linked issue:\n%s", text)
+	}
+	html := bodies["text/html"]
+	if !strings.Contains(html, "Fix crash – ünïcode") || !strings.Contains(html, "https://github.com/acme/app/issues/4") || !strings.Contains(html, "Activity since") {
+		t.Errorf("html part missing the items or footer:\n%s", html)
+	}
+}
+
+func TestLastDigest(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	if last, err := db.LastDigest(); err != nil || !last.IsZero() {
+		t.Errorf("LastDigest() on a new database = %v, %v, want zero", last, err)
+	}
+	sent := time.Date(2026, 10, 18, 7, 30, 0, 0, time.UTC)
+	if err := db.SetLastDigest(sent); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := db.LastDigest(); err != nil || !last.Equal(sent) {
+		t.Errorf("LastDigest() = %v, %v, want %v", last, err, sent)
+	}
+}
+
+// fakeSMTPServer accepts one message without TLS and sends what it received on the channel
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var transcript strings.Builder
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					transcript.WriteString(line)
+				}
+				reply("250 queued")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				transcript.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSendDigest(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	msg := []byte("Subject: test\r\n\r\nhello\r\n")
+
+	// A localhost server without STARTTLS is allowed
+	err := sendDigest(smtpOptions{addr: addr, startTLS: true}, "Feed <feed@example.com>", []string{"me@example.com", "you@example.com"}, msg)
+	if err != nil {
+		t.Fatalf("sendDigest() error = %v", err)
+	}
+	transcript := <-received
+	for _, want := range []string{"MAIL FROM:<feed@example.com>", "RCPT TO:<me@example.com>", "RCPT TO:<you@example.com>", "Subject: test"} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript missing %q:\n%s", want, transcript)
+		}
+	}
+
+	if err := sendDigest(smtpOptions{addr: "no-port"}, "a@example.com", []string{"b@example.com"}, msg); err == nil {
+		t.Error("sendDigest() accepted an address without a port")
+	}
+}
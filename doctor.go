@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/v57/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DoctorProblem describes a single bad record found by Doctor
+type DoctorProblem struct {
+	Bucket string
+	Key    string
+	Reason string
+}
+
+// DoctorReport is the result of scanning the database
+type DoctorReport struct {
+	Scanned     map[string]int
+	Problems    []DoctorProblem
+	Quarantined int
+	// DroppedIndex counts index entries deleted instead of quarantined
+	DroppedIndex int
+}
+
+// isValidItemKey reports whether key has the exact buildItemKey format
+func isValidItemKey(key string) bool {
+	owner, repo, number, err := parseItemRef(key)
+	return err == nil && buildItemKey(owner, repo, number) == key
+}
+
+// commentParentKey returns the item key a buildCommentKey-style key belongs to
+func commentParentKey(key string) (string, bool) {
+	hash := strings.LastIndex(key, "#")
+	if hash < 0 {
+		return "", false
+	}
+	parts := strings.Split(key[hash+1:], "/")
+	if len(parts) != 3 || parts[1] == "" {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+		return "", false
+	}
+	parent := key[:hash+1+len(parts[0])]
+	return parent, isValidItemKey(parent)
+}
+
+// historyParentKey returns the item key a buildHistoryKey-style key belongs to
+func historyParentKey(key string) (string, bool) {
+	at := strings.LastIndex(key, "@")
+	if at < 0 {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(key[at+1:], 10, 64); err != nil {
+		return "", false
+	}
+	parent := key[:at]
+	return parent, isValidItemKey(parent)
+}
+
+// hookFiredParts splits a hookFiredKey into its item key and updated_at
+func hookFiredParts(key string) (string, string, bool) {
+	last := strings.LastIndex(key, "|")
+	if last < 0 {
+		return "", "", false
+	}
+	first := strings.LastIndex(key[:last], "|")
+	if first <= 0 {
+		return "", "", false
+	}
+	return key[first+1 : last], key[last+1:], true
+}
+
+// recordValidator returns why a record is bad, or "" when it is fine
+type recordValidator func(key string, value []byte) string
+
+// doctorValidators returns the validator of every bucket in allBuckets except
+// corrupt, which keeps quarantined records as they were found
+func doctorValidators(tx *bolt.Tx) map[string]recordValidator {
+	// Records of unreadable items are orphaned along with them, and index
+	// entries must point at the updated_at of a readable item
+	readable := make(map[string]string)
+	for _, bucket := range [][]byte{pullRequestsBucket, issuesBucket} {
+		kind := itemKind(bucket)
+		tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			if snapshot, err := snapshotFromRecord(kind, v); err == nil {
+				readable[string(bucket)+"|"+string(k)] = snapshot["updated_at"]
+			}
+			return nil
+		})
+	}
+	prExists := func(key string) bool {
+		_, ok := readable[string(pullRequestsBucket)+"|"+key]
+		return ok
+	}
+	itemExists := func(key string) bool {
+		_, ok := readable[string(issuesBucket)+"|"+key]
+		return ok || prExists(key)
+	}
+	checkIndexed := func(bucket, updatedAt, itemKey string) string {
+		if itemKind([]byte(bucket)) == "" || !isValidItemKey(itemKey) {
+			return "key does not match the index key format"
+		}
+		current, ok := readable[bucket+"|"+itemKey]
+		if !ok {
+			return fmt.Sprintf("index entry for a missing or unreadable item (%s/%s)", bucket, itemKey)
+		}
+		if current != updatedAt {
+			return fmt.Sprintf("stale index entry (%s was updated at %s)", itemKey, current)
+		}
+		return ""
+	}
+
+	checkTime := func(what string, value []byte) string {
+		if _, err := time.Parse(time.RFC3339, string(value)); err != nil {
+			return fmt.Sprintf("unparsable %s: %v", what, err)
+		}
+		return ""
+	}
+
+	checkItem := func(kind string) recordValidator {
+		return func(key string, value []byte) string {
+			if !isValidItemKey(key) {
+				return "key does not match owner/repo#N format"
+			}
+			if _, err := snapshotFromRecord(kind, value); err != nil {
+				return fmt.Sprintf("unparsable %s record: %v", kind, err)
+			}
+			return ""
+		}
+	}
+
+	return map[string]recordValidator{
+		string(pullRequestsBucket): checkItem("PR"),
+		string(issuesBucket):       checkItem("issue"),
+
+		string(commentsBucket): func(key string, value []byte) string {
+			parent, ok := commentParentKey(key)
+			if !ok {
+				return "key does not match owner/repo#N/type/id format"
+			}
+			var err error
+			if strings.Contains(key, "/pr_review_comment/") {
+				err = json.Unmarshal(value, &github.PullRequestComment{})
+			} else {
+				err = json.Unmarshal(value, &github.IssueComment{})
+			}
+			if err != nil {
+				return fmt.Sprintf("unparsable comment: %v", err)
+			}
+			if !itemExists(parent) {
+				return fmt.Sprintf("orphaned comment (no PR or issue %s)", parent)
+			}
+			return ""
+		},
+
+		string(historyBucket): func(key string, value []byte) string {
+			parent, ok := historyParentKey(key)
+			if !ok {
+				return "key does not match owner/repo#N@timestamp format"
+			}
+			if err := json.Unmarshal(value, &HistoryEntry{}); err != nil {
+				return fmt.Sprintf("unparsable history entry: %v", err)
+			}
+			if !itemExists(parent) {
+				return fmt.Sprintf("orphaned history entry (no PR or issue %s)", parent)
+			}
+			return ""
+		},
+
+		string(timelinesBucket): func(key string, value []byte) string {
+			if !isValidItemKey(key) {
+				return "key does not match owner/repo#N format"
+			}
+			if err := json.Unmarshal(value, &prTimeline{}); err != nil {
+				return fmt.Sprintf("unparsable timeline: %v", err)
+			}
+			if !prExists(key) {
+				return fmt.Sprintf("orphaned timeline (no PR %s)", key)
+			}
+			return ""
+		},
+
+		// Webhooks may mark items the next fetch hasn't cached yet, so marks
+		// without an item are fine
+		string(updatesBucket): func(key string, value []byte) string {
+			if !isValidItemKey(key) {
+				return "key does not match owner/repo#N format"
+			}
+			return checkTime("update mark", value)
+		},
+
+		string(hooksFiredBucket): func(key string, value []byte) string {
+			itemKey, updatedAt, ok := hookFiredParts(key)
+			if !ok || !isValidItemKey(itemKey) {
+				return "key does not match hook|owner/repo#N|updated_at format"
+			}
+			if reason := checkTime("updated_at in key", []byte(updatedAt)); reason != "" {
+				return reason
+			}
+			return checkTime("fired time", value)
+		},
+
+		// Keys this version doesn't know may come from a newer one and are kept
+		string(metaBucket): func(key string, value []byte) string {
+			if key == string(lastDigestKey) {
+				return checkTime("last digest time", value)
+			}
+			return ""
+		},
+
+		string(updatedIndexBucket): func(key string, value []byte) string {
+			parts := strings.SplitN(key, "|", 3)
+			if len(parts) != 3 {
+				return "key does not match bucket|updated_at|owner/repo#N format"
+			}
+			return checkIndexed(parts[0], parts[1], parts[2])
+		},
+
+		string(repoIndexBucket): func(key string, value []byte) string {
+			parts := strings.SplitN(key, "|", 4)
+			if len(parts) != 4 {
+				return "key does not match bucket|owner/repo|updated_at|owner/repo#N format"
+			}
+			if owner, repo, _, err := parseItemRef(parts[3]); err == nil && strings.ToLower(owner+"/"+repo) != parts[1] {
+				return fmt.Sprintf("index entry for %s filed under repo %s", parts[3], parts[1])
+			}
+			return checkIndexed(parts[0], parts[2], parts[3])
+		},
+	}
+}
+
+// isIndexBucket reports whether bucket holds index entries derived from the items
+func isIndexBucket(bucket string) bool {
+	return bucket == string(updatedIndexBucket) || bucket == string(repoIndexBucket)
+}
+
+// Doctor validates every record in every bucket: unparsable values, malformed
+// keys, records whose parent item is missing and index entries that don't
+// match their item. With quarantine set, bad records are moved to the corrupt
+// bucket under "<bucket>/<key>", along with dropping the index entries of
+// quarantined items. Bad index entries are dropped rather than quarantined
+// since they are rebuilt from the items.
+func (d *Database) Doctor(quarantine bool) (*DoctorReport, error) {
+	report := &DoctorReport{Scanned: make(map[string]int)}
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		validators := doctorValidators(tx)
+		for _, bucket := range allBuckets {
+			b := tx.Bucket(bucket)
+			if b == nil {
+				// A read-only snapshot of a database from before the bucket existed
+				continue
+			}
+			validate := validators[string(bucket)]
+			err := b.ForEach(func(k, v []byte) error {
+				report.Scanned[string(bucket)]++
+				if validate == nil {
+					return nil
+				}
+				if reason := validate(string(k), v); reason != "" {
+					report.Problems = append(report.Problems, DoctorProblem{Bucket: string(bucket), Key: string(k), Reason: reason})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !quarantine || len(report.Problems) == 0 {
+		return report, nil
+	}
+	if d.readOnly {
+		return report, fmt.Errorf("cannot quarantine records in a read-only database")
+	}
+
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		corrupt := tx.Bucket(corruptBucket)
+		for _, p := range report.Problems {
+			b := tx.Bucket([]byte(p.Bucket))
+			key := []byte(p.Key)
+
+			if isIndexBucket(p.Bucket) {
+				// Index values are empty, so look for the key itself; it may
+				// already be gone with a quarantined item
+				if k, _ := b.Cursor().Seek(key); !bytes.Equal(k, key) {
+					continue
+				}
+				if err := b.Delete(key); err != nil {
+					return fmt.Errorf("failed to remove %s/%s: %w", p.Bucket, p.Key, err)
+				}
+				report.DroppedIndex++
+				continue
+			}
+
+			data := b.Get(key)
+			if data == nil {
+				continue
+			}
+			if err := corrupt.Put([]byte(p.Bucket+"/"+p.Key), data); err != nil {
+				return fmt.Errorf("failed to quarantine %s/%s: %w", p.Bucket, p.Key, err)
+			}
+			if err := b.Delete(key); err != nil {
+				return fmt.Errorf("failed to remove %s/%s: %w", p.Bucket, p.Key, err)
+			}
+			report.Quarantined++
+
+			if itemKind([]byte(p.Bucket)) != "" {
+				dropped, err := unindexItem(tx, []byte(p.Bucket), p.Key)
+				if err != nil {
+					return fmt.Errorf("failed to unindex %s/%s: %w", p.Bucket, p.Key, err)
+				}
+				report.DroppedIndex += dropped
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func runDBCommand(args []string) error {
+	if len(args) == 0 || args[0] != "doctor" {
+		return fmt.Errorf("usage: github-feed db doctor [--quarantine]")
+	}
+
+	fs := flag.NewFlagSet("db doctor", flag.ExitOnError)
+	quarantine := fs.Bool("quarantine", false, "Move bad records to the 'corrupt' bucket")
+	fs.Parse(args[1:])
+
+	var db *Database
+	var err error
+	if *quarantine {
+		db, err = openCacheDatabase()
+	} else {
+		db, err = openCacheDatabaseReadOnly()
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := db.Doctor(*quarantine)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Scanned records:")
+	for _, bucket := range allBuckets {
+		fmt.Printf("  %-14s %d\n", string(bucket), report.Scanned[string(bucket)])
+	}
+	fmt.Println()
+
+	if len(report.Problems) == 0 {
+		fmt.Println(color.New(color.FgGreen).Sprint("No problems found"))
+		return nil
+	}
+
+	warningColor := color.New(color.FgYellow, color.Bold)
+	fmt.Println(warningColor.Sprintf("Found %d problem(s):", len(report.Problems)))
+	for _, p := range report.Problems {
+		fmt.Printf("  %s/%s: %s\n", p.Bucket, p.Key, p.Reason)
+	}
+
+	if *quarantine {
+		fmt.Printf("\nQuarantined %d record(s) to the '%s' bucket\n", report.Quarantined, string(corruptBucket))
+		if report.DroppedIndex > 0 {
+			fmt.Printf("Dropped %d stale index entries, they are rebuilt from the items\n", report.DroppedIndex)
+		}
+	} else {
+		fmt.Println("\nRun 'github-feed db doctor --quarantine' to move them to the 'corrupt' bucket.")
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDoctor(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	issue := &github.Issue{Number: github.Int(1), Title: github.String("ok")}
+	if err := db.SaveIssueWithLabel("owner", "repo", issue, "Authored", false); err != nil {
+		t.Fatalf("SaveIssueWithLabel() error = %v", err)
+	}
+
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		prs := tx.Bucket(pullRequestsBucket)
+		if err := prs.Put([]byte("owner/repo#2"), []byte("{not json")); err != nil {
+			return err
+		}
+		if err := prs.Put([]byte("bad-key"), []byte("{}")); err != nil {
+			return err
+		}
+		comments := tx.Bucket(commentsBucket)
+		if err := comments.Put([]byte("owner/repo#1/issue_comment/10"), []byte(`{"id":10}`)); err != nil {
+			return err
+		}
+		return comments.Put([]byte("owner/repo#9/pr_review_comment/11"), []byte(`{"id":11}`))
+	})
+	if err != nil {
+		t.Fatalf("seeding database: %v", err)
+	}
+
+	report, err := db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor() error = %v", err)
+	}
+
+	bad := map[string]bool{}
+	for _, p := range report.Problems {
+		bad[p.Bucket+"/"+p.Key] = true
+	}
+	for _, want := range []string{
+		"pull_requests/owner/repo#2",
+		"pull_requests/bad-key",
+		"comments/owner/repo#9/pr_review_comment/11",
+	} {
+		if !bad[want] {
+			t.Errorf("Doctor() did not report %s, got %+v", want, report.Problems)
+		}
+	}
+	if len(report.Problems) != 3 {
+		t.Errorf("Doctor() reported %d problems, want 3: %+v", len(report.Problems), report.Problems)
+	}
+
+	prs, _, err := db.GetAllPullRequestsWithLabels(false)
+	if err != nil {
+		t.Fatalf("GetAllPullRequestsWithLabels() should skip bad records, got error %v", err)
+	}
+	if _, ok := prs["owner/repo#2"]; ok {
+		t.Errorf("unparsable PR should have been skipped")
+	}
+
+	report, err = db.Doctor(true)
+	if err != nil {
+		t.Fatalf("Doctor(true) error = %v", err)
+	}
+	if report.Quarantined != 3 {
+		t.Errorf("Doctor(true) quarantined %d records, want 3", report.Quarantined)
+	}
+
+	report, err = db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor() after quarantine error = %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("Doctor() after quarantine reported %+v, want none", report.Problems)
+	}
+}
+
+func TestDoctorAllBuckets(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	updated := github.Timestamp{Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	pr := &github.PullRequest{Number: github.Int(1), Title: github.String("ok"), UpdatedAt: &updated}
+	if err := db.SavePullRequestWithLabel("owner", "repo", pr, "Authored", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+	stale := &github.PullRequest{Number: github.Int(2), Title: github.String("later corrupted"), UpdatedAt: &updated}
+	if err := db.SavePullRequestWithLabel("owner", "repo", stale, "Authored", false); err != nil {
+		t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+	}
+
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		puts := []struct {
+			bucket     []byte
+			key, value string
+		}{
+			{pullRequestsBucket, "owner/repo#2", "{not json"},
+			{timelinesBucket, "owner/repo#1", `{"events":[]}`},
+			{timelinesBucket, "owner/repo#9", `{"events":[]}`},
+			{timelinesBucket, "owner/repo#3", "{not json"},
+			{updatesBucket, "owner/repo#7", "2026-03-01T12:00:00Z"},
+			{updatesBucket, "owner/repo#1", "yesterday"},
+			{hooksFiredBucket, "notify|owner/repo#1|2026-03-01T12:00:00Z", "2026-03-01T12:05:00Z"},
+			{hooksFiredBucket, "notify|owner/repo#1", "2026-03-01T12:05:00Z"},
+			{metaBucket, "last_digest", "never"},
+			{updatedIndexBucket, "pull_requests|2026-02-01T00:00:00Z|owner/repo#1", ""},
+			{repoIndexBucket, "pull_requests|other/repo|2026-03-01T12:00:00Z|owner/repo#1", ""},
+		}
+		for _, p := range puts {
+			if err := tx.Bucket(p.bucket).Put([]byte(p.key), []byte(p.value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("seeding database: %v", err)
+	}
+
+	report, err := db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor() error = %v", err)
+	}
+
+	db.db.View(func(tx *bolt.Tx) error {
+		validators := doctorValidators(tx)
+		for _, bucket := range allBuckets {
+			if _, ok := validators[string(bucket)]; !ok && !bytes.Equal(bucket, corruptBucket) {
+				t.Errorf("doctorValidators() has no validator for bucket %s", bucket)
+			}
+		}
+		return nil
+	})
+
+	bad := map[string]bool{}
+	for _, p := range report.Problems {
+		if p.Bucket == string(historyBucket) {
+			// The unreadable PR's history is orphaned along with it
+			bad["history/"+p.Key[:strings.Index(p.Key, "@")]] = true
+			continue
+		}
+		bad[p.Bucket+"/"+p.Key] = true
+	}
+	want := []string{
+		"pull_requests/owner/repo#2",
+		"history/owner/repo#2",
+		"timelines/owner/repo#9",
+		"timelines/owner/repo#3",
+		"updates/owner/repo#1",
+		"hooks_fired/notify|owner/repo#1",
+		"meta/last_digest",
+		"idx_updated/pull_requests|2026-02-01T00:00:00Z|owner/repo#1",
+		"idx_updated/pull_requests|2026-03-01T12:00:00Z|owner/repo#2",
+		"idx_repo/pull_requests|other/repo|2026-03-01T12:00:00Z|owner/repo#1",
+		"idx_repo/pull_requests|owner/repo|2026-03-01T12:00:00Z|owner/repo#2",
+	}
+	for _, key := range want {
+		if !bad[key] {
+			t.Errorf("Doctor() did not report %s, got %+v", key, report.Problems)
+		}
+	}
+	if len(report.Problems) != len(want) {
+		t.Errorf("Doctor() reported %d problems, want %d: %+v", len(report.Problems), len(want), report.Problems)
+	}
+
+	report, err = db.Doctor(true)
+	if err != nil {
+		t.Fatalf("Doctor(true) error = %v", err)
+	}
+	if report.Quarantined != 7 || report.DroppedIndex != 4 {
+		t.Errorf("Doctor(true) quarantined %d and dropped %d index entries, want 7 and 4", report.Quarantined, report.DroppedIndex)
+	}
+
+	err = db.db.View(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{updatedIndexBucket, repoIndexBucket} {
+			tx.Bucket(name).ForEach(func(k, v []byte) error {
+				if strings.HasSuffix(string(k), "|owner/repo#2") {
+					t.Errorf("index entry %s/%s of the quarantined PR was left behind", name, k)
+				}
+				return nil
+			})
+		}
+		if tx.Bucket(corruptBucket).Get([]byte("idx_updated/pull_requests|2026-02-01T00:00:00Z|owner/repo#1")) != nil {
+			t.Errorf("stale index entry should be dropped, not quarantined")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading indexes: %v", err)
+	}
+
+	report, err = db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor() after quarantine error = %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("Doctor() after quarantine reported %+v, want none", report.Problems)
+	}
+}
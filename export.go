@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// exportColumns is the header row of the CSV and TSV output
+var exportColumns = []string{
+	"kind", "parent", "owner", "repo", "number", "title", "user", "label", "state",
+	"has_updates", "url", "created_at", "updated_at", "closed_at", "merged_at",
+	"gh_labels", "milestone",
+}
+
+// exportLabels joins GitHub label names with ";" for a single column
+func exportLabels(labels []*github.Label) string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return spreadsheetSafe(strings.Join(names, ";"))
+}
+
+// formatExportTime returns ts in RFC3339 UTC, or "" when unknown
+func formatExportTime(ts *github.Timestamp) string {
+	if ts == nil || ts.IsZero() {
+		return ""
+	}
+	return ts.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// spreadsheetSafe keeps spreadsheet apps from evaluating free text as a formula
+func spreadsheetSafe(text string) string {
+	if text != "" && strings.ContainsAny(text[:1], "=+-@") {
+		return "'" + text
+	}
+	return text
+}
+
+func prExportRow(activity PRActivity) []string {
+	pr := activity.PR
+	return []string{
+		"pr",
+		"",
+		activity.Owner,
+		activity.Repo,
+		strconv.Itoa(pr.GetNumber()),
+		spreadsheetSafe(pr.GetTitle()),
+		pr.User.GetLogin(),
+		activity.Label,
+		prState(activity),
+		strconv.FormatBool(activity.HasUpdates),
+		pr.GetHTMLURL(),
+		formatExportTime(pr.CreatedAt),
+		formatExportTime(pr.UpdatedAt),
+		formatExportTime(pr.ClosedAt),
+		formatExportTime(pr.MergedAt),
+		exportLabels(pr.Labels),
+		spreadsheetSafe(pr.Milestone.GetTitle()),
+	}
+}
+
+// issueExportRow flattens an issue; parent is the owner/repo#N of the PR it is linked to, if any
+func issueExportRow(issue IssueActivity, parent string) []string {
+	i := issue.Issue
+	return []string{
+		"issue",
+		parent,
+		issue.Owner,
+		issue.Repo,
+		strconv.Itoa(i.GetNumber()),
+		spreadsheetSafe(i.GetTitle()),
+		i.User.GetLogin(),
+		issue.Label,
+		i.GetState(),
+		strconv.FormatBool(issue.HasUpdates),
+		i.GetHTMLURL(),
+		formatExportTime(i.CreatedAt),
+		formatExportTime(i.UpdatedAt),
+		formatExportTime(i.ClosedAt),
+		"",
+		exportLabels(i.Labels),
+		spreadsheetSafe(i.Milestone.GetTitle()),
+	}
+}
+
+// renderDelimited writes one row per PR, linked issue and standalone issue,
+// separated by comma or tab
+func renderDelimited(w io.Writer, sections FeedSections, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+
+	for _, activities := range [][]PRActivity{sections.OpenPRs, sections.ClosedPRs} {
+		for _, activity := range activities {
+			if err := cw.Write(prExportRow(activity)); err != nil {
+				return err
+			}
+			parent := activity.Owner + "/" + activity.Repo + "#" + strconv.Itoa(activity.PR.GetNumber())
+			for _, issue := range activity.Issues {
+				if err := cw.Write(issueExportRow(issue, parent)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, issues := range [][]IssueActivity{sections.OpenIssues, sections.ClosedIssues} {
+		for _, issue := range issues {
+			if err := cw.Write(issueExportRow(issue, "")); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
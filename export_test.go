@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestRenderDelimited(t *testing.T) {
+	updated := &github.Timestamp{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	linked := IssueActivity{
+		Owner: "o", Repo: "r", Label: "Mentioned",
+		Issue: &github.Issue{Number: github.Int(2), Title: github.String("Crash, on \"start\""), State: github.String("open"), UpdatedAt: updated},
+	}
+	sections := FeedSections{
+		ClosedPRs: []PRActivity{{
+			Owner: "o", Repo: "r", Label: "Authored",
+			PR:     &github.PullRequest{Number: github.Int(1), Title: github.String("=SUM(A1)"), State: github.String("closed"), Merged: github.Bool(true), UpdatedAt: updated, MergedAt: updated},
+			Issues: []IssueActivity{linked},
+		}},
+	}
+
+	var sb strings.Builder
+	if err := renderDelimited(&sb, sections, ','); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v\n%s", err, sb.String())
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want header + PR + linked issue", len(rows))
+	}
+
+	col := func(row []string, name string) string {
+		for i, c := range exportColumns {
+			if c == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("no column %q", name)
+		return ""
+	}
+	if got := col(rows[1], "title"); got != "'=SUM(A1)" {
+		t.Errorf("PR title = %q, want formula-escaped", got)
+	}
+	if got := col(rows[1], "state"); got != "merged" {
+		t.Errorf("PR state = %q, want merged", got)
+	}
+	if got := col(rows[1], "merged_at"); got != "2024-03-01T12:00:00Z" {
+		t.Errorf("PR merged_at = %q", got)
+	}
+	if got := col(rows[2], "parent"); got != "o/r#1" {
+		t.Errorf("linked issue parent = %q, want o/r#1", got)
+	}
+	if got := col(rows[2], "title"); got != "Crash, on \"start\"" {
+		t.Errorf("linked issue title = %q", got)
+	}
+}
+
+func TestPRExportRowSearchResult(t *testing.T) {
+	pr := searchResultPR(t, `{"number": 3, "title": "Old fix", "state": "closed",
+		"created_at": "2026-09-01T10:00:00Z", "updated_at": "2026-09-03T10:00:00Z", "closed_at": "2026-09-02T10:00:00Z",
+		"labels": [{"name": "bug"}, {"name": "docs"}], "milestone": {"title": "v1.0"}}`)
+	row := prExportRow(PRActivity{Owner: "o", Repo: "r", Label: "Authored", PR: pr})
+
+	want := map[string]string{
+		"created_at": "2026-09-01T10:00:00Z",
+		"closed_at":  "2026-09-02T10:00:00Z",
+		"gh_labels":  "bug;docs",
+		"milestone":  "v1.0",
+	}
+	for i, name := range exportColumns {
+		if value, ok := want[name]; ok && row[i] != value {
+			t.Errorf("%s = %q, want %q", name, row[i], value)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedEntry is one PR or issue update in an RSS or Atom feed
+type feedEntry struct {
+	GUID       string
+	Title      string
+	Link       string
+	Author     string
+	Updated    time.Time
+	Categories []string
+	Content    string
+}
+
+// feedEntryGUID is stable for an item until it is updated again
+func feedEntryGUID(itemKey string, updated time.Time) string {
+	return fmt.Sprintf("%s@%s", itemKey, updated.UTC().Format(time.RFC3339))
+}
+
+// buildFeedEntries returns cached PRs and issues updated inside window, newest
+// first, capped at limit entries (0 means no limit)
+func buildFeedEntries(db *Database, window TimeWindow, limit int) ([]feedEntry, error) {
+	var entries []feedEntry
+
+	prs, prLabels, err := db.GetPullRequestsWithLabelsSince(window.Since, allowedRepoList(), false)
+	if err != nil {
+		return nil, err
+	}
+	for key, pr := range prs {
+		updated := pr.GetUpdatedAt().Time
+		owner, repo, _, err := parseItemRef(key)
+		if err != nil || !window.contains(updated) || !isRepoAllowed(owner, repo) {
+			continue
+		}
+		state := pr.GetState()
+		if pr.GetMerged() {
+			state = "merged"
+		}
+		entries = append(entries, feedEntry{
+			GUID:       feedEntryGUID(key, updated),
+			Title:      feedEntryTitle(prLabels[key], key, pr.GetTitle()),
+			Link:       pr.GetHTMLURL(),
+			Author:     pr.User.GetLogin(),
+			Updated:    updated,
+			Categories: feedCategories("pull request", prLabels[key], state),
+			Content:    pr.GetBody(),
+		})
+	}
+
+	issues, issueLabels, err := db.GetIssuesWithLabelsSince(window.Since, allowedRepoList(), false)
+	if err != nil {
+		return nil, err
+	}
+	for key, issue := range issues {
+		updated := issue.GetUpdatedAt().Time
+		owner, repo, _, err := parseItemRef(key)
+		if err != nil || !window.contains(updated) || !isRepoAllowed(owner, repo) {
+			continue
+		}
+		entries = append(entries, feedEntry{
+			GUID:       feedEntryGUID(key, updated),
+			Title:      feedEntryTitle(issueLabels[key], key, issue.GetTitle()),
+			Link:       issue.GetHTMLURL(),
+			Author:     issue.User.GetLogin(),
+			Updated:    updated,
+			Categories: feedCategories("issue", issueLabels[key], issue.GetState()),
+			Content:    issue.GetBody(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Updated.Equal(entries[j].Updated) {
+			return entries[i].GUID < entries[j].GUID
+		}
+		return entries[i].Updated.After(entries[j].Updated)
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func feedEntryTitle(label, key, title string) string {
+	if label == "" {
+		return fmt.Sprintf("%s: %s", key, title)
+	}
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(label), key, title)
+}
+
+func feedCategories(kind, label, state string) []string {
+	categories := []string{kind}
+	if label != "" {
+		categories = append(categories, label)
+	}
+	if state != "" {
+		categories = append(categories, state)
+	}
+	return categories
+}
+
+// dublinCoreNamespace provides dc:creator, since RSS 2.0 <author> must be an email address
+const dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+// writeRSS writes entries as an RSS 2.0 document
+func writeRSS(w io.Writer, title string, entries []feedEntry) error {
+	doc := rssDocument{
+		Version: "2.0",
+		DC:      dublinCoreNamespace,
+		Channel: rssChannel{
+			Title:         title,
+			Link:          "https://github.com",
+			Description:   "Pull requests and issues from github-feed",
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, e := range entries {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			GUID:        rssGUID{IsPermaLink: "false", Value: e.GUID},
+			PubDate:     e.Updated.UTC().Format(time.RFC1123Z),
+			Creator:     e.Author,
+			Categories:  e.Categories,
+			Description: e.Content,
+		})
+	}
+	return writeXML(w, doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Link       atomLink       `xml:"link"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+// atomID turns a GUID into a valid Atom id
+func atomID(guid string) string {
+	return "urn:github-feed:" + url.PathEscape(guid)
+}
+
+// writeAtom writes entries as an Atom 1.0 document
+func writeAtom(w io.Writer, title string, entries []feedEntry) error {
+	updated := time.Now()
+	if len(entries) > 0 {
+		updated = entries[0].Updated
+	}
+
+	feed := atomFeed{
+		Title:   title,
+		ID:      atomID("feed"),
+		Updated: updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: "https://github.com"},
+	}
+	for _, e := range entries {
+		entry := atomEntry{
+			Title:   e.Title,
+			ID:      atomID(e.GUID),
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: e.Link, Rel: "alternate"},
+			Author:  atomAuthor{Name: e.Author},
+			Content: atomContent{Type: "text", Value: e.Content},
+		}
+		for _, c := range e.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// runFeedCommand implements both "rss" and "atom"
+func runFeedCommand(format string, args []string) error {
+	fs := flag.NewFlagSet(format, flag.ExitOnError)
+	outPath := fs.String("out", "", "Write the feed to this file instead of stdout")
+	timeRangeStr := fs.String("time", "1m", "Include items updated in this time range (1h, 1w3d, 4m, yesterday, last-sprint, ...)")
+	since := fs.String("since", "", "Include items updated on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "Include items updated on or before this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", 200, "Maximum number of entries (0 for no limit)")
+	fs.Parse(args)
+
+	// The feed honors the allowed, excluded and org repo settings like the main feed
+	if _, err := loadCommandSettings(); err != nil {
+		return err
+	}
+	config.repoFilter = newRepoFilter(settingValue("allowed-repos"), settingValue("exclude-repos"), settingValue("org"))
+
+	window, err := parseTimeWindow(*timeRangeStr, *since, *until, time.Now())
+	if err != nil {
+		return err
+	}
+
+	db, err := openCacheDatabaseReadOnly()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := buildFeedEntries(db, window, *limit)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	title := "GitHub Feed"
+	if format == "atom" {
+		err = writeAtom(w, title, entries)
+	} else {
+		err = writeRSS(w, title, entries)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *outPath != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d entries to %s\n", len(entries), *outPath)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestBuildFeedEntries(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	savedFilter := config.repoFilter
+	defer func() { config.repoFilter = savedFilter }()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	ts := func(d time.Duration) *github.Timestamp { return &github.Timestamp{Time: now.Add(-d)} }
+	for _, item := range []struct {
+		repo    string
+		number  int
+		updated time.Duration
+	}{
+		{"app", 1, time.Hour},
+		{"app", 2, 2 * time.Hour},
+		{"app", 3, 30 * 24 * time.Hour},
+		{"mint", 4, 3 * time.Hour},
+	} {
+		pr := &github.PullRequest{Number: github.Int(item.number), Title: github.String("PR"), State: github.String("open"),
+			UpdatedAt: ts(item.updated), User: &github.User{Login: github.String("bob")}}
+		if err := db.SavePullRequestWithLabel("acme", item.repo, pr, "Authored", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issue := &github.Issue{Number: github.Int(5), Title: github.String("Issue"), State: github.String("open"), UpdatedAt: ts(90 * time.Minute)}
+	if err := db.SaveIssueWithLabel("acme", "app", issue, "Mentioned", false); err != nil {
+		t.Fatal(err)
+	}
+
+	guids := func(entries []feedEntry) string {
+		var keys []string
+		for _, e := range entries {
+			keys = append(keys, e.GUID[:strings.Index(e.GUID, "@")])
+		}
+		return strings.Join(keys, ",")
+	}
+	window := TimeWindow{Since: now.Add(-7 * 24 * time.Hour)}
+	tests := []struct {
+		name   string
+		filter RepoFilter
+		window TimeWindow
+		limit  int
+		want   string
+	}{
+		{"window", RepoFilter{}, window, 0, "acme/app#1,acme/app#5,acme/app#2,acme/mint#4"},
+		{"limit", RepoFilter{}, window, 2, "acme/app#1,acme/app#5"},
+		{"until", RepoFilter{}, TimeWindow{Since: window.Since, Until: now.Add(-100 * time.Minute)}, 0, "acme/app#2,acme/mint#4"},
+		{"excluded repo", newRepoFilter("", "acme/mint", ""), window, 0, "acme/app#1,acme/app#5,acme/app#2"},
+		{"allowed repo", newRepoFilter("acme/mint", "", ""), window, 0, "acme/mint#4"},
+	}
+	for _, tt := range tests {
+		config.repoFilter = tt.filter
+		entries, err := buildFeedEntries(db, tt.window, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := guids(entries); got != tt.want {
+			t.Errorf("%s: entries = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testFeedEntries() []feedEntry {
+	updated := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	return []feedEntry{{
+		GUID:       feedEntryGUID("acme/app#5", updated),
+		Title:      feedEntryTitle("Review Requested", "acme/app#5", `Fix <b>bold</b> & "quotes"`),
+		Link:       "https://github.com/acme/app/pull/5?a=1&b=2",
+		Author:     "bob",
+		Updated:    updated,
+		Categories: feedCategories("pull request", "Review Requested", "open"),
+		Content:    "Body with <script>alert(1)</script>",
+	}}
+}
+
+func TestWriteRSS(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeRSS(&out, "GitHub Feed", testFeedEntries()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "<b>") || strings.Contains(out.String(), "<script>") {
+		t.Errorf("markup in titles or bodies is not escaped:\n%s", out.String())
+	}
+
+	var doc rssDocument
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("RSS is not valid XML: %v\n%s", err, out.String())
+	}
+	if doc.Version != "2.0" || len(doc.Channel.Items) != 1 {
+		t.Fatalf("RSS = %+v, want version 2.0 with one item", doc)
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != `[REVIEW REQUESTED] acme/app#5: Fix <b>bold</b> & "quotes"` || item.Link != "https://github.com/acme/app/pull/5?a=1&b=2" {
+		t.Errorf("item = %+v, want the title and link to round-trip", item)
+	}
+	if item.GUID.Value != "acme/app#5@2026-10-18T09:00:00Z" || item.GUID.IsPermaLink != "false" || item.PubDate != "Sun, 18 Oct 2026 09:00:00 +0000" {
+		t.Errorf("guid/pubDate = %+v %q", item.GUID, item.PubDate)
+	}
+	if strings.Join(item.Categories, ",") != "pull request,Review Requested,open" {
+		t.Errorf("categories = %v", item.Categories)
+	}
+	// Logins go in dc:creator; RSS <author> must be an email address
+	for _, want := range []string{`xmlns:dc="http://purl.org/dc/elements/1.1/"`, "<dc:creator>bob</dc:creator>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("RSS missing %s:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "<author>") {
+		t.Errorf("RSS has an <author> without an email address:\n%s", out.String())
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeAtom(&out, "GitHub Feed", testFeedEntries()); err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(out.Bytes(), &feed); err != nil {
+		t.Fatalf("Atom is not valid XML: %v\n%s", err, out.String())
+	}
+	if feed.XMLName.Space != "http://www.w3.org/2005/Atom" || feed.Updated != "2026-10-18T09:00:00Z" || len(feed.Entries) != 1 {
+		t.Fatalf("feed = %+v, want the Atom namespace, the newest entry's time and one entry", feed)
+	}
+	entry := feed.Entries[0]
+	if entry.ID != "urn:github-feed:acme%2Fapp%235@2026-10-18T09:00:00Z" || strings.ContainsAny(entry.ID, "#/ ") {
+		t.Errorf("entry id = %q, want a valid URN", entry.ID)
+	}
+	if entry.Content.Value != "Body with <script>alert(1)</script>" || entry.Content.Type != "text" {
+		t.Errorf("content = %+v, want the body as text", entry.Content)
+	}
+	if strings.Contains(out.String(), "<script>") {
+		t.Errorf("content is not escaped:\n%s", out.String())
+	}
+}
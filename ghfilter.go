@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/v57/github"
+)
+
+// ItemFilter holds the GitHub label, milestone and author filters and the feed
+// label filter. Labels and authors within one flag match any of the listed values.
+type ItemFilter struct {
+	feedLabels    []string // --label
+	labels        []string // --gh-label
+	excludeLabels []string // --exclude-gh-label
+	milestone     string   // --milestone
+	authors       []string // --author
+	notAuthors    []string // --not-author
+}
+
+func newItemFilter(labels, excludeLabels, milestone, authors, notAuthors, feedLabels string) ItemFilter {
+	return ItemFilter{
+		feedLabels:    splitList(feedLabels),
+		labels:        splitList(labels),
+		excludeLabels: splitList(excludeLabels),
+		milestone:     strings.TrimSpace(milestone),
+		authors:       splitList(authors),
+		notAuthors:    splitList(notAuthors),
+	}
+}
+
+func (f ItemFilter) isEmpty() bool {
+	return len(f.labels) == 0 && len(f.excludeLabels) == 0 && f.milestone == "" &&
+		len(f.authors) == 0 && len(f.notAuthors) == 0 && len(f.feedLabels) == 0
+}
+
+// matchesFeedLabel reports whether an item's feed label (Authored, Reviewed, ...) passes --label
+func (f ItemFilter) matchesFeedLabel(label string) bool {
+	return len(f.feedLabels) == 0 || containsFold(f.feedLabels, label)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether an item with these labels, milestone and author passes the filter
+func (f ItemFilter) matches(labels []*github.Label, milestone *github.Milestone, author string) bool {
+	if len(f.authors) > 0 && !containsFold(f.authors, author) {
+		return false
+	}
+	if containsFold(f.notAuthors, author) {
+		return false
+	}
+	if f.milestone != "" && !strings.EqualFold(milestone.GetTitle(), f.milestone) {
+		return false
+	}
+
+	hasWanted := len(f.labels) == 0
+	for _, label := range labels {
+		if containsFold(f.excludeLabels, label.GetName()) {
+			return false
+		}
+		if containsFold(f.labels, label.GetName()) {
+			hasWanted = true
+		}
+	}
+	return hasWanted
+}
+
+func (f ItemFilter) matchesPR(pr *github.PullRequest) bool {
+	return f.matches(pr.Labels, pr.Milestone, pr.User.GetLogin())
+}
+
+func (f ItemFilter) matchesIssue(issue *github.Issue) bool {
+	return f.matches(issue.Labels, issue.Milestone, issue.User.GetLogin())
+}
+
+// searchAuthor converts a bot login such as dependabot[bot] to the app/ form search expects
+func searchAuthor(login string) string {
+	if name, ok := strings.CutSuffix(login, "[bot]"); ok {
+		return "app/" + name
+	}
+	return login
+}
+
+// searchQualifiers returns the label:, milestone: and author: qualifiers for the filter
+func (f ItemFilter) searchQualifiers() string {
+	var quals []string
+	if len(f.labels) > 0 {
+		quoted := make([]string, len(f.labels))
+		for i, label := range f.labels {
+			quoted[i] = strconv.Quote(label)
+		}
+		// A comma-separated label: value matches any of the labels
+		quals = append(quals, "label:"+strings.Join(quoted, ","))
+	}
+	for _, label := range f.excludeLabels {
+		quals = append(quals, "-label:"+strconv.Quote(label))
+	}
+	if f.milestone != "" {
+		quals = append(quals, "milestone:"+strconv.Quote(f.milestone))
+	}
+	// Several author: qualifiers would all have to match, so a list is filtered locally
+	if len(f.authors) == 1 {
+		quals = append(quals, "author:"+searchAuthor(f.authors[0]))
+	}
+	for _, author := range f.notAuthors {
+		quals = append(quals, "-author:"+searchAuthor(author))
+	}
+	return strings.Join(quals, " ")
+}
+
+// withQualifiers appends the filter's qualifiers to query when they fit within
+// GitHub's query length limit
+func (f ItemFilter) withQualifiers(query string) string {
+	quals := f.searchQualifiers()
+	if quals == "" || len(query)+1+len(quals) > maxSearchQueryLength {
+		return query
+	}
+	return query + " " + quals
+}
+
+// filterFeedItems drops PRs and issues, including issues linked to PRs, that
+// don't match config.itemFilter. The feed label filter only applies to top-level
+// items, so a matching PR keeps its linked issues.
+func filterFeedItems(activities []PRActivity, issues []IssueActivity) ([]PRActivity, []IssueActivity) {
+	f := config.itemFilter
+	if f.isEmpty() {
+		return activities, issues
+	}
+
+	keptPRs := activities[:0]
+	for _, activity := range activities {
+		if !f.matchesFeedLabel(activity.Label) || !f.matchesPR(activity.PR) {
+			continue
+		}
+		var linked []IssueActivity
+		for _, issue := range activity.Issues {
+			if f.matchesIssue(issue.Issue) {
+				linked = append(linked, issue)
+			}
+		}
+		activity.Issues = linked
+		keptPRs = append(keptPRs, activity)
+	}
+
+	keptIssues := issues[:0]
+	for _, issue := range issues {
+		if f.matchesFeedLabel(issue.Label) && f.matchesIssue(issue.Issue) {
+			keptIssues = append(keptIssues, issue)
+		}
+	}
+	return keptPRs, keptIssues
+}
+
+// parseLabelColor parses a GitHub label color such as "d73a4a"
+func parseLabelColor(hex string) (r, g, b int, ok bool) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(value >> 16 & 0xff), int(value >> 8 & 0xff), int(value & 0xff), true
+}
+
+// labelTextIsDark reports whether black text reads better than white on the label color
+func labelTextIsDark(r, g, b int) bool {
+	return (299*r+587*g+114*b)/1000 > 150
+}
+
+// labelChip renders a GitHub label with its own color as background, or as
+// [name] when colors are off
+func labelChip(label *github.Label) string {
+	name := label.GetName()
+	r, g, b, ok := parseLabelColor(label.GetColor())
+	if color.NoColor || !ok {
+		return "[" + name + "]"
+	}
+	chip := color.BgRGB(r, g, b)
+	if labelTextIsDark(r, g, b) {
+		chip.AddRGB(0, 0, 0)
+	} else {
+		chip.AddRGB(255, 255, 255)
+	}
+	return chip.Sprint(" " + name + " ")
+}
+
+// labelChips renders all labels separated by spaces
+func labelChips(labels []*github.Label) string {
+	chips := make([]string, 0, len(labels))
+	for _, label := range labels {
+		chips = append(chips, labelChip(label))
+	}
+	return strings.Join(chips, " ")
+}
+
+// labelCSS returns the background and text colors of a label for the HTML report
+func labelCSS(label *github.Label) (background, text string) {
+	r, g, b, ok := parseLabelColor(label.GetColor())
+	if !ok {
+		return "#777777", "#ffffff"
+	}
+	text = "#ffffff"
+	if labelTextIsDark(r, g, b) {
+		text = "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b), text
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func testLabels(names ...string) []*github.Label {
+	var labels []*github.Label
+	for _, name := range names {
+		labels = append(labels, &github.Label{Name: github.String(name), Color: github.String("d73a4a")})
+	}
+	return labels
+}
+
+func TestItemFilterMatches(t *testing.T) {
+	v1 := &github.Milestone{Title: github.String("v1.0")}
+
+	tests := []struct {
+		name      string
+		filter    ItemFilter
+		labels    []*github.Label
+		milestone *github.Milestone
+		author    string
+		want      bool
+	}{
+		{"empty filter", newItemFilter("", "", "", "", "", ""), nil, nil, "alice", true},
+		{"any of labels", newItemFilter("bug,priority/high", "", "", "", "", ""), testLabels("Priority/High"), nil, "alice", true},
+		{"missing label", newItemFilter("bug", "", "", "", "", ""), testLabels("docs"), nil, "alice", false},
+		{"excluded label", newItemFilter("", "wontfix", "", "", "", ""), testLabels("bug", "wontfix"), nil, "alice", false},
+		{"milestone", newItemFilter("", "", "v1.0", "", "", ""), nil, v1, "alice", true},
+		{"other milestone", newItemFilter("", "", "v2.0", "", "", ""), nil, v1, "alice", false},
+		{"no milestone", newItemFilter("", "", "v1.0", "", "", ""), nil, nil, "alice", false},
+		{"author", newItemFilter("", "", "", "alice,bob", "", ""), nil, nil, "Bob", true},
+		{"other author", newItemFilter("", "", "", "alice", "", ""), nil, nil, "bob", false},
+		{"bot hidden", newItemFilter("", "", "", "", "dependabot[bot]", ""), nil, nil, "dependabot[bot]", false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matches(tt.labels, tt.milestone, tt.author); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestItemFilterSearchQualifiers(t *testing.T) {
+	f := newItemFilter("bug,good first issue", "wontfix", "v1.0", "alice", "dependabot[bot]", "")
+	want := `label:"bug","good first issue" -label:"wontfix" milestone:"v1.0" author:alice -author:app/dependabot`
+	if got := f.searchQualifiers(); got != want {
+		t.Errorf("searchQualifiers() = %q, want %q", got, want)
+	}
+
+	if got := newItemFilter("", "", "", "alice,bob", "", "").searchQualifiers(); got != "" {
+		t.Errorf("several authors should be filtered locally, got %q", got)
+	}
+}
+
+func TestFilterFeedItems(t *testing.T) {
+	saved := config.itemFilter
+	defer func() { config.itemFilter = saved }()
+	config.itemFilter = newItemFilter("bug", "", "", "", "", "")
+
+	bugIssue := IssueActivity{Issue: &github.Issue{Number: github.Int(1), Labels: testLabels("bug")}}
+	docsIssue := IssueActivity{Issue: &github.Issue{Number: github.Int(2), Labels: testLabels("docs")}}
+	activities := []PRActivity{
+		{PR: &github.PullRequest{Number: github.Int(10), Labels: testLabels("bug")}, Issues: []IssueActivity{bugIssue, docsIssue}},
+		{PR: &github.PullRequest{Number: github.Int(11)}},
+	}
+
+	prs, issues := filterFeedItems(activities, []IssueActivity{bugIssue, docsIssue})
+	if len(prs) != 1 || prs[0].PR.GetNumber() != 10 {
+		t.Fatalf("filterFeedItems() kept PRs %+v, want only #10", prs)
+	}
+	if len(prs[0].Issues) != 1 || prs[0].Issues[0].Issue.GetNumber() != 1 {
+		t.Errorf("filterFeedItems() kept linked issues %+v, want only #1", prs[0].Issues)
+	}
+	if len(issues) != 1 || issues[0].Issue.GetNumber() != 1 {
+		t.Errorf("filterFeedItems() kept issues %+v, want only #1", issues)
+	}
+}
+
+// searchResultPR decodes an item as the search API returns it and builds the PR
+// record from it like collectSearchResults does
+func searchResultPR(t *testing.T, item string) *github.PullRequest {
+	t.Helper()
+	var issue github.Issue
+	if err := json.Unmarshal([]byte(item), &issue); err != nil {
+		t.Fatal(err)
+	}
+	return prFromIssue(&issue)
+}
+
+// A trimmed search API item for a PR
+const searchPRItem = `{
+  "number": 12, "title": "Fix crash", "state": "open", "html_url": "https://github.com/acme/app/pull/12",
+  "user": {"login": "bob"}, "assignees": [{"login": "alice"}],
+  "labels": [{"name": "bug", "color": "d73a4a"}], "milestone": {"title": "v1.0"},
+  "created_at": "2026-09-01T10:00:00Z", "updated_at": "2026-10-01T10:00:00Z",
+  "pull_request": {"url": "https://api.github.com/repos/acme/app/pulls/12"}
+}`
+
+func TestFilterFeedItemsSearchResults(t *testing.T) {
+	saved := config.itemFilter
+	defer func() { config.itemFilter = saved }()
+
+	pr := searchResultPR(t, searchPRItem)
+	if pr.GetCreatedAt().IsZero() || len(pr.Assignees) != 1 {
+		t.Errorf("prFromIssue() = %+v, want created_at and assignees copied", pr)
+	}
+	if item := prAPIItem(PRActivity{PR: pr}); len(item.GHLabels) != 1 || item.Milestone != "v1.0" {
+		t.Errorf("prAPIItem() = %+v, want the bug label and milestone for hooks and /api/items", item)
+	}
+	tests := []struct {
+		filter ItemFilter
+		want   bool
+	}{
+		{newItemFilter("bug", "", "", "", "", ""), true},
+		{newItemFilter("docs", "", "", "", "", ""), false},
+		{newItemFilter("", "bug", "", "", "", ""), false},
+		{newItemFilter("", "", "v1.0", "", "", ""), true},
+		{newItemFilter("", "", "v2.0", "", "", ""), false},
+	}
+	for _, tt := range tests {
+		config.itemFilter = tt.filter
+		prs, _ := filterFeedItems([]PRActivity{{PR: pr}}, nil)
+		if got := len(prs) == 1; got != tt.want {
+			t.Errorf("filter %+v kept the search result = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestFilterFeedLabels(t *testing.T) {
+	saved := config.itemFilter
+	defer func() { config.itemFilter = saved }()
+	config.itemFilter = newItemFilter("", "", "", "", "", "review requested,Reviewed")
+
+	linked := IssueActivity{Label: "Mentioned", Issue: &github.Issue{Number: github.Int(3)}}
+	activities := []PRActivity{
+		{Label: "Review Requested", PR: &github.PullRequest{Number: github.Int(10)}, Issues: []IssueActivity{linked}},
+		{Label: "Authored", PR: &github.PullRequest{Number: github.Int(11)}},
+	}
+	issues := []IssueActivity{{Label: "Mentioned", Issue: &github.Issue{Number: github.Int(1)}}}
+
+	prs, issues := filterFeedItems(activities, issues)
+	if len(prs) != 1 || prs[0].PR.GetNumber() != 10 || len(prs[0].Issues) != 1 {
+		t.Errorf("filterFeedItems() kept PRs %+v, want #10 with its linked issue", prs)
+	}
+	if len(issues) != 0 {
+		t.Errorf("filterFeedItems() kept issues %+v, want none", issues)
+	}
+}
+
+func TestLabelChip(t *testing.T) {
+	setNoColor(t, true)
+	if got := labelChip(testLabels("bug")[0]); got != "[bug]" {
+		t.Errorf("labelChip() without color = %q, want [bug]", got)
+	}
+
+	setNoColor(t, false)
+	if got, want := labelChip(testLabels("bug")[0]), "\x1b[48;2;215;58;74;38;2;255;255;255m bug "; !strings.HasPrefix(got, want) {
+		t.Errorf("labelChip() = %q, want prefix %q", got, want)
+	}
+	light := &github.Label{Name: github.String("docs"), Color: github.String("fef2c0")}
+	if bg, text := labelCSS(light); bg != "#fef2c0" || text != "#000000" {
+		t.Errorf("labelCSS(light) = %s, %s, want dark text", bg, text)
+	}
+}
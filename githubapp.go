@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// appTokenRefreshMargin is how long before expiry an installation token is replaced
+const appTokenRefreshMargin = 5 * time.Minute
+
+// parseAppPrivateKey parses a GitHub App private key in PKCS#1 or PKCS#8 PEM form
+func parseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+}
+
+// appJWT returns the RS256-signed JWT a GitHub App authenticates with. It is
+// backdated a minute for clock drift and valid for nine, under GitHub's ten.
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appTokenSource mints installation tokens for a GitHub App and replaces them
+// shortly before they expire
+type appTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	apiURL         string // API base URL with trailing slash; empty means api.github.com
+	now            func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a valid installation token, minting a new one when needed
+func (s *appTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != "" && now.Before(s.expires.Add(-appTokenRefreshMargin)) {
+		return s.token, nil
+	}
+
+	jwt, err := appJWT(s.appID, s.key, now)
+	if err != nil {
+		return "", fmt.Errorf("signing GitHub App JWT: %w", err)
+	}
+	// The app client must not use appTransport, or minting would recurse
+	client := github.NewClient(nil).WithAuthToken(jwt)
+	if s.apiURL != "" {
+		if client.BaseURL, err = url.Parse(s.apiURL); err != nil {
+			return "", err
+		}
+	}
+	installationToken, _, err := client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating installation token for app %d, installation %d: %w", s.appID, s.installationID, err)
+	}
+
+	s.token = installationToken.GetToken()
+	s.expires = installationToken.GetExpiresAt().Time
+	if config.debugMode {
+		fmt.Printf("  [GitHubApp] Minted installation token, expires %s\n", s.expires.Format(time.RFC3339))
+	}
+	return s.token, nil
+}
+
+// Expires returns when the current installation token expires
+func (s *appTokenSource) Expires() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expires
+}
+
+// appTransport authenticates every request with a current installation token
+type appTransport struct {
+	source *appTokenSource
+	base   http.RoundTripper
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// client returns a github.Client that refreshes its installation token as needed
+func (s *appTokenSource) client() *github.Client {
+	return github.NewClient(&http.Client{Transport: &appTransport{source: s, base: &metricsTransport{base: http.DefaultTransport}}})
+}
+
+// newAppTokenSource builds a token source from the app-* settings, or returns
+// nil when no app ID is configured
+func newAppTokenSource() (*appTokenSource, error) {
+	appIDValue := settingValue("app-id")
+	if appIDValue == "" {
+		return nil, nil
+	}
+	appID, err := strconv.ParseInt(appIDValue, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app-id %q", appIDValue)
+	}
+	installationValue := settingValue("app-installation-id")
+	if installationValue == "" {
+		return nil, errors.New("app-installation-id is required with app-id")
+	}
+	installationID, err := strconv.ParseInt(installationValue, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app-installation-id %q", installationValue)
+	}
+
+	keyPEM := []byte(settingValue("app-private-key"))
+	if len(keyPEM) == 0 {
+		keyFile := settingValue("app-private-key-file")
+		if keyFile == "" {
+			return nil, errors.New("app-private-key-file or GITHUB_APP_PRIVATE_KEY is required with app-id")
+		}
+		if keyPEM, err = os.ReadFile(keyFile); err != nil {
+			return nil, fmt.Errorf("reading GitHub App private key: %w", err)
+		}
+	}
+	key, err := parseAppPrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub App private key: %w", err)
+	}
+
+	return &appTokenSource{appID: appID, installationID: installationID, key: key, now: time.Now}, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAppKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseAppPrivateKey(t *testing.T) {
+	key := testAppKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := parseAppPrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Fatalf("%s: %v", block.Type, err)
+		}
+		if !parsed.Equal(key) {
+			t.Errorf("%s: parsed a different key", block.Type)
+		}
+	}
+
+	if _, err := parseAppPrivateKey([]byte("not a key")); err == nil {
+		t.Error("parseAppPrivateKey() accepted non-PEM data")
+	}
+}
+
+func TestAppJWT(t *testing.T) {
+	key := testAppKey(t)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	jwt, err := appJWT(12345, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("appJWT() = %q, want three parts", jwt)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "12345" || claims.Iat != now.Unix()-60 || claims.Exp != now.Unix()+540 {
+		t.Errorf("claims = %+v, want iss 12345, iat now-60s, exp now+9m", claims)
+	}
+}
+
+func TestAppTokenSourceRefresh(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	minted := 0
+	var lastAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/42/access_tokens":
+			minted++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, minted, now.Add(time.Hour).Format(time.RFC3339))
+		default:
+			lastAuth = r.Header.Get("Authorization")
+			fmt.Fprint(w, `{"resources":{"core":{"limit":5000,"remaining":4999}}}`)
+		}
+	}))
+	defer server.Close()
+
+	source := &appTokenSource{
+		appID:          1,
+		installationID: 42,
+		key:            testAppKey(t),
+		apiURL:         server.URL + "/",
+		now:            func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if token, err := source.Token(ctx); err != nil || token != "ghs_1" {
+			t.Fatalf("Token() = %q, %v, want ghs_1", token, err)
+		}
+	}
+	if minted != 1 {
+		t.Errorf("minted %d tokens, want 1 while the token is fresh", minted)
+	}
+
+	// Within the refresh margin of expiry a new token is minted
+	now = now.Add(time.Hour - appTokenRefreshMargin)
+	if token, _ := source.Token(ctx); token != "ghs_2" {
+		t.Errorf("Token() near expiry = %q, want ghs_2", token)
+	}
+
+	client := source.client()
+	baseURL, err := url.Parse(source.apiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+	if _, _, err := client.RateLimit.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lastAuth != "Bearer ghs_2" {
+		t.Errorf("API request authorization = %q, want Bearer ghs_2", lastAuth)
+	}
+}
@@ -5,12 +5,13 @@ go 1.25
 require (
 	github.com/fatih/color v1.18.0
 	github.com/google/go-github/v57 v57.0.0
+	github.com/mattn/go-isatty v0.0.20
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/sys v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	go.etcd.io/bbolt v1.4.3 // indirect
-	golang.org/x/sys v0.29.0 // indirect
 )
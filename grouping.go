@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// groupByModes lists the values accepted by --group-by
+var groupByModes = []string{"state", "repo", "label", "author", "none"}
+
+// sortFields lists the values accepted by --sort, each optionally prefixed with "-"
+var sortFields = []string{"updated", "created", "number", "repo", "label-priority"}
+
+func isValidGroupBy(mode string) bool {
+	for _, m := range groupByModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSortFlag splits a --sort value into its field and whether "-" reversed it
+func parseSortFlag(value string) (field string, reverse bool, err error) {
+	field = strings.TrimPrefix(value, "-")
+	reverse = field != value
+	for _, f := range sortFields {
+		if f == field {
+			return field, reverse, nil
+		}
+	}
+	return "", false, fmt.Errorf("invalid --sort %q (use %s, optionally prefixed with -)", value, strings.Join(sortFields, ", "))
+}
+
+// itemSortKey holds everything a PR or issue can be sorted by
+type itemSortKey struct {
+	updated  time.Time
+	created  time.Time
+	number   int
+	repo     string
+	priority int
+}
+
+func prSortKey(activity PRActivity) itemSortKey {
+	return itemSortKey{
+		updated:  activity.UpdatedAt,
+		created:  activity.PR.GetCreatedAt().Time,
+		number:   activity.PR.GetNumber(),
+		repo:     strings.ToLower(activity.Owner + "/" + activity.Repo),
+		priority: getPRLabelPriority(activity.Label),
+	}
+}
+
+func issueSortKey(issue IssueActivity) itemSortKey {
+	return itemSortKey{
+		updated:  issue.UpdatedAt,
+		created:  issue.Issue.GetCreatedAt().Time,
+		number:   issue.Issue.GetNumber(),
+		repo:     strings.ToLower(issue.Owner + "/" + issue.Repo),
+		priority: getIssueLabelPriority(issue.Label),
+	}
+}
+
+// compareSortKeys orders a before b (negative) in the natural order of field:
+// newest first for updated and created, ascending for number, repo and
+// label-priority. Ties fall back to newest updated first.
+func compareSortKeys(a, b itemSortKey, field string) int {
+	cmp := 0
+	switch field {
+	case "created":
+		cmp = compareTimesDesc(a.created, b.created)
+	case "number":
+		cmp = a.number - b.number
+	case "repo":
+		cmp = strings.Compare(a.repo, b.repo)
+	case "label-priority":
+		cmp = a.priority - b.priority
+	}
+	if cmp == 0 {
+		cmp = compareTimesDesc(a.updated, b.updated)
+	}
+	return cmp
+}
+
+func compareTimesDesc(a, b time.Time) int {
+	switch {
+	case a.After(b):
+		return -1
+	case a.Before(b):
+		return 1
+	}
+	return 0
+}
+
+// sortActivities sorts PRs by field, reversed when reverse is set. Demoted PRs
+// always come last.
+func sortActivities(activities []PRActivity, field string, reverse bool) {
+	sort.SliceStable(activities, func(i, j int) bool {
+		if activities[i].Demoted != activities[j].Demoted {
+			return activities[j].Demoted
+		}
+		cmp := compareSortKeys(prSortKey(activities[i]), prSortKey(activities[j]), field)
+		if reverse {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// sortIssues is the issue counterpart of sortActivities
+func sortIssues(issues []IssueActivity, field string, reverse bool) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Demoted != issues[j].Demoted {
+			return issues[j].Demoted
+		}
+		cmp := compareSortKeys(issueSortKey(issues[i]), issueSortKey(issues[j]), field)
+		if reverse {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// feedGroup is one --group-by group, split into the usual state sections
+type feedGroup struct {
+	Name     string
+	Sections FeedSections
+}
+
+func (g feedGroup) summary() string {
+	prs := len(g.Sections.OpenPRs) + len(g.Sections.ClosedPRs)
+	issues := len(g.Sections.OpenIssues) + len(g.Sections.ClosedIssues)
+	return fmt.Sprintf("%s (%d PRs, %d issues)", g.Name, prs, issues)
+}
+
+// groupName returns the group an item belongs to for mode
+func groupName(mode, owner, repo, label, author string) string {
+	switch mode {
+	case "repo":
+		return owner + "/" + repo
+	case "label":
+		return label
+	case "author":
+		return author
+	}
+	return ""
+}
+
+// groupFeed splits sections into groups for the repo, label and author modes,
+// keeping the sort order within each group. Linked issues stay with their PR.
+func groupFeed(sections FeedSections, mode string) []feedGroup {
+	byName := make(map[string]*feedGroup)
+	var order []string
+	group := func(name string) *FeedSections {
+		if name == "" {
+			name = "(none)"
+		}
+		g, ok := byName[name]
+		if !ok {
+			g = &feedGroup{Name: name}
+			byName[name] = g
+			order = append(order, name)
+		}
+		return &g.Sections
+	}
+
+	for _, activity := range sections.OpenPRs {
+		s := group(groupName(mode, activity.Owner, activity.Repo, activity.Label, activity.PR.User.GetLogin()))
+		s.OpenPRs = append(s.OpenPRs, activity)
+	}
+	for _, activity := range sections.ClosedPRs {
+		s := group(groupName(mode, activity.Owner, activity.Repo, activity.Label, activity.PR.User.GetLogin()))
+		s.ClosedPRs = append(s.ClosedPRs, activity)
+	}
+	for _, issue := range sections.OpenIssues {
+		s := group(groupName(mode, issue.Owner, issue.Repo, issue.Label, issue.Issue.User.GetLogin()))
+		s.OpenIssues = append(s.OpenIssues, issue)
+	}
+	for _, issue := range sections.ClosedIssues {
+		s := group(groupName(mode, issue.Owner, issue.Repo, issue.Label, issue.Issue.User.GetLogin()))
+		s.ClosedIssues = append(s.ClosedIssues, issue)
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if mode == "label" {
+			pi, pj := getPRLabelPriority(order[i]), getPRLabelPriority(order[j])
+			if pi != pj {
+				return pi < pj
+			}
+		}
+		return strings.ToLower(order[i]) < strings.ToLower(order[j])
+	})
+
+	groups := make([]feedGroup, 0, len(order))
+	for _, name := range order {
+		groups = append(groups, *byName[name])
+	}
+	return groups
+}
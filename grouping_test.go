@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func testPRActivity(owner, repo string, number int, label, author string, updated time.Time) PRActivity {
+	return PRActivity{
+		Owner: owner, Repo: repo, Label: label, UpdatedAt: updated,
+		PR: &github.PullRequest{Number: github.Int(number), State: github.String("open"), User: &github.User{Login: github.String(author)}},
+	}
+}
+
+func TestParseSortFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		field   string
+		reverse bool
+		wantErr bool
+	}{
+		{value: "updated", field: "updated"},
+		{value: "-number", field: "number", reverse: true},
+		{value: "label-priority", field: "label-priority"},
+		{value: "-label-priority", field: "label-priority", reverse: true},
+		{value: "size", wantErr: true},
+	}
+	for _, tt := range tests {
+		field, reverse, err := parseSortFlag(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSortFlag(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if field != tt.field || reverse != tt.reverse {
+			t.Errorf("parseSortFlag(%q) = %q, %v, want %q, %v", tt.value, field, reverse, tt.field, tt.reverse)
+		}
+	}
+}
+
+func TestSortActivities(t *testing.T) {
+	now := time.Now()
+	activities := []PRActivity{
+		testPRActivity("b", "x", 5, "Mentioned", "u", now.Add(-time.Hour)),
+		testPRActivity("a", "y", 9, "Authored", "u", now.Add(-2*time.Hour)),
+		testPRActivity("a", "y", 2, "Review Requested", "u", now),
+	}
+
+	numbers := func() []int {
+		var result []int
+		for _, a := range activities {
+			result = append(result, a.PR.GetNumber())
+		}
+		return result
+	}
+	tests := []struct {
+		field   string
+		reverse bool
+		want    []int
+	}{
+		{"updated", false, []int{2, 5, 9}},
+		{"updated", true, []int{9, 5, 2}},
+		{"number", false, []int{2, 5, 9}},
+		{"number", true, []int{9, 5, 2}},
+		{"repo", false, []int{2, 9, 5}},
+		{"label-priority", false, []int{9, 2, 5}},
+	}
+	for _, tt := range tests {
+		sortActivities(activities, tt.field, tt.reverse)
+		got := numbers()
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("sort %s reverse=%v = %v, want %v", tt.field, tt.reverse, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGroupFeed(t *testing.T) {
+	now := time.Now()
+	sections := FeedSections{
+		OpenPRs: []PRActivity{
+			testPRActivity("minio", "ec", 1, "Review Requested", "bob", now),
+			testPRActivity("minio", "minio", 2, "Authored", "alice", now),
+			testPRActivity("minio", "ec", 3, "Authored", "alice", now),
+		},
+	}
+
+	groups := groupFeed(sections, "repo")
+	if len(groups) != 2 || groups[0].Name != "minio/ec" || len(groups[0].Sections.OpenPRs) != 2 {
+		t.Fatalf("groupFeed(repo) = %+v", groups)
+	}
+	if groups[0].Sections.OpenPRs[0].PR.GetNumber() != 1 {
+		t.Errorf("groupFeed(repo) did not keep the sort order within a group")
+	}
+
+	groups = groupFeed(sections, "label")
+	if len(groups) != 2 || groups[0].Name != "Authored" || groups[1].Name != "Review Requested" {
+		t.Errorf("groupFeed(label) should order groups by label priority, got %+v", groups)
+	}
+}
+
+func TestSortActivitiesCreatedSearchResults(t *testing.T) {
+	item := func(number int, created string) PRActivity {
+		pr := searchResultPR(t, fmt.Sprintf(`{"number": %d, "state": "open", "created_at": %q, "updated_at": "2026-10-01T10:00:00Z"}`, number, created))
+		return PRActivity{Owner: "o", Repo: "r", PR: pr, UpdatedAt: pr.GetUpdatedAt().Time}
+	}
+	// Same updated_at, so only created_at decides the order
+	activities := []PRActivity{
+		item(1, "2026-09-10T10:00:00Z"),
+		item(2, "2026-09-20T10:00:00Z"),
+		item(3, "2026-09-01T10:00:00Z"),
+	}
+	sortActivities(activities, "created", false)
+	if got := [3]int{activities[0].PR.GetNumber(), activities[1].PR.GetNumber(), activities[2].PR.GetNumber()}; got != [3]int{2, 1, 3} {
+		t.Errorf("sort created = %v, want newest created first [2 1 3]", got)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/v57/github"
+)
+
+// FieldChange is a single field that differs between two snapshots of an item
+type FieldChange struct {
+	Field string `json:"f"`
+	Old   string `json:"o,omitempty"`
+	New   string `json:"n,omitempty"`
+}
+
+// HistoryEntry records the fields that changed when an item was saved
+type HistoryEntry struct {
+	Timestamp time.Time     `json:"t"`
+	Kind      string        `json:"k"`
+	Label     string        `json:"l,omitempty"`
+	FirstSeen bool          `json:"s,omitempty"`
+	Changes   []FieldChange `json:"c"`
+}
+
+// buildHistoryKey creates a key that sorts history entries of one item chronologically
+func buildHistoryKey(itemKey string, ts time.Time) string {
+	return fmt.Sprintf("%s@%020d", itemKey, ts.UnixNano())
+}
+
+// bodyDigest returns a short hash so body edits are tracked without storing the body twice
+func bodyDigest(body string) string {
+	if body == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:4])
+}
+
+func labelNames(labels []*github.Label) string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func userLogins(users []*github.User) string {
+	logins := make([]string, 0, len(users))
+	for _, u := range users {
+		logins = append(logins, u.GetLogin())
+	}
+	sort.Strings(logins)
+	return strings.Join(logins, ",")
+}
+
+func formatTimestamp(ts *github.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.UTC().Format(time.RFC3339)
+}
+
+// prSnapshot extracts the tracked fields of a PR
+func prSnapshot(pr *github.PullRequest) map[string]string {
+	snap := map[string]string{
+		"title":      pr.GetTitle(),
+		"state":      pr.GetState(),
+		"body":       bodyDigest(pr.GetBody()),
+		"labels":     labelNames(pr.Labels),
+		"assignees":  userLogins(pr.Assignees),
+		"milestone":  pr.GetMilestone().GetTitle(),
+		"updated_at": formatTimestamp(pr.UpdatedAt),
+	}
+	if pr.Merged != nil {
+		snap["merged"] = strconv.FormatBool(pr.GetMerged())
+	}
+	return snap
+}
+
+// issueSnapshot extracts the tracked fields of an issue
+func issueSnapshot(issue *github.Issue) map[string]string {
+	return map[string]string{
+		"title":      issue.GetTitle(),
+		"state":      issue.GetState(),
+		"body":       bodyDigest(issue.GetBody()),
+		"labels":     labelNames(issue.Labels),
+		"assignees":  userLogins(issue.Assignees),
+		"milestone":  issue.GetMilestone().GetTitle(),
+		"comments":   strconv.Itoa(issue.GetComments()),
+		"updated_at": formatTimestamp(issue.UpdatedAt),
+	}
+}
+
+// snapshotFromRecord decodes a stored PR or issue record into its tracked fields
+func snapshotFromRecord(kind string, data []byte) (map[string]string, error) {
+	if kind == "PR" {
+		var prWithLabel PRWithLabel
+		if err := json.Unmarshal(data, &prWithLabel); err == nil && prWithLabel.PR != nil {
+			return prSnapshot(prWithLabel.PR), nil
+		}
+		var pr github.PullRequest
+		if err := json.Unmarshal(data, &pr); err != nil {
+			return nil, err
+		}
+		return prSnapshot(&pr), nil
+	}
+
+	var issueWithLabel IssueWithLabel
+	if err := json.Unmarshal(data, &issueWithLabel); err == nil && issueWithLabel.Issue != nil {
+		return issueSnapshot(issueWithLabel.Issue), nil
+	}
+	var issue github.Issue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return nil, err
+	}
+	return issueSnapshot(&issue), nil
+}
+
+// diffSnapshots returns the changed fields sorted by name. A nil old snapshot
+// records every non-empty field of the new one (first time the item was seen).
+func diffSnapshots(old, new map[string]string) []FieldChange {
+	var changes []FieldChange
+	for field, newValue := range new {
+		oldValue, existed := old[field]
+		if old == nil {
+			if newValue != "" {
+				changes = append(changes, FieldChange{Field: field, New: newValue})
+			}
+			continue
+		}
+		// Fields missing from older snapshots (e.g. "merged" from search results) are not changes
+		if !existed || oldValue == newValue {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// updateMarkerFired reports whether the changes would make the "●" marker show,
+// which happens when the API updated_at is newer than the cached one
+func updateMarkerFired(entry HistoryEntry) bool {
+	for _, c := range entry.Changes {
+		if c.Field == "updated_at" && c.Old != "" && c.New > c.Old {
+			return true
+		}
+	}
+	return false
+}
+
+func runHistoryCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: github-feed history owner/repo#N")
+	}
+
+	owner, repo, number, err := parseItemRef(args[0])
+	if err != nil {
+		return err
+	}
+
+	db, err := openCacheDatabaseReadOnly()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := db.GetHistory(owner, repo, number)
+	if err != nil {
+		return err
+	}
+
+	key := buildItemKey(owner, repo, number)
+	if len(entries) == 0 {
+		fmt.Printf("No history recorded for %s\n", key)
+		return nil
+	}
+
+	titleColor := color.New(color.FgHiCyan, color.Bold)
+	fmt.Println(titleColor.Sprintf("HISTORY FOR %s (%s):", key, entries[0].Kind))
+	fmt.Println("------------------------------------------")
+
+	markerColor := color.New(color.FgYellow, color.Bold)
+	fieldColor := color.New(color.FgCyan)
+	for _, entry := range entries {
+		prefix := "  "
+		if updateMarkerFired(entry) {
+			prefix = markerColor.Sprint("● ")
+		}
+
+		heading := "changed"
+		if entry.FirstSeen {
+			heading = "first seen"
+		}
+		fmt.Printf("%s%s %s", prefix, entry.Timestamp.Local().Format("2006/01/02 15:04:05"), heading)
+		if entry.Label != "" {
+			fmt.Printf(" as %s", getLabelColor(entry.Label).Sprint(strings.ToUpper(entry.Label)))
+		}
+		fmt.Println()
+
+		onlyUpdatedAt := true
+		for _, c := range entry.Changes {
+			if c.Field != "updated_at" {
+				onlyUpdatedAt = false
+			}
+			switch {
+			case c.Field == "body" && c.Old != "":
+				fmt.Printf("      %s edited\n", fieldColor.Sprint(c.Field))
+			case entry.FirstSeen:
+				fmt.Printf("      %s: %s\n", fieldColor.Sprint(c.Field), c.New)
+			default:
+				fmt.Printf("      %s: %q -> %q\n", fieldColor.Sprint(c.Field), c.Old, c.New)
+			}
+		}
+		if onlyUpdatedAt && updateMarkerFired(entry) {
+			fmt.Println("      (activity not captured in tracked fields, e.g. new comments or reviews)")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	old := map[string]string{"title": "Fix bug", "state": "open", "labels": ""}
+	new := map[string]string{"title": "Fix bug", "state": "closed", "labels": "bug", "merged": "true"}
+
+	changes := diffSnapshots(old, new)
+	want := []FieldChange{
+		{Field: "labels", Old: "", New: "bug"},
+		{Field: "state", Old: "open", New: "closed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("diffSnapshots() returned %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestDiffSnapshotsFirstSeen(t *testing.T) {
+	changes := diffSnapshots(nil, map[string]string{"title": "New", "labels": ""})
+	if len(changes) != 1 || changes[0].Field != "title" || changes[0].New != "New" {
+		t.Errorf("diffSnapshots(nil, ...) = %+v, want only the non-empty title", changes)
+	}
+}
+
+func TestParseItemRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		owner   string
+		repo    string
+		number  int
+		wantErr bool
+	}{
+		{"minio/minio#123", "minio", "minio", 123, false},
+		{"owner/repo.name#1", "owner", "repo.name", 1, false},
+		{"owner/repo", "", "", 0, true},
+		{"owner#1", "", "", 0, true},
+		{"owner/repo#abc", "", "", 0, true},
+		{"owner/repo#", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			owner, repo, number, err := parseItemRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseItemRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if owner != tt.owner || repo != tt.repo || number != tt.number {
+				t.Errorf("parseItemRef(%q) = %s, %s, %d, want %s, %s, %d",
+					tt.ref, owner, repo, number, tt.owner, tt.repo, tt.number)
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// hooksFiredBucket maps "<hook>|<item key>|<updated_at>" to when the hook fired,
+// so each hook runs at most once per item update
+var hooksFiredBucket = []byte("hooks_fired")
+
+const (
+	hookTimeout = 30 * time.Second
+	// hookFiredRetention is how long fired records are kept, well past any feed window
+	hookFiredRetention = 180 * 24 * time.Hour
+)
+
+// defaultHookMessage is the text posted to incoming webhooks without a message template
+const defaultHookMessage = "{{.Label}}: {{.Owner}}/{{.Repo}}#{{.Number}} {{.Title}}\n{{.URL}}"
+
+// hookConfig is one entry under hooks: in config.yaml
+type hookConfig struct {
+	Name    string `yaml:"name"`
+	When    string `yaml:"when"`
+	Command string `yaml:"command"`
+	Webhook string `yaml:"webhook"`
+	Message string `yaml:"message"`
+}
+
+// Hook runs a command or posts to an incoming webhook for feed items matching
+// all of its conditions
+type Hook struct {
+	Name       string
+	conditions []hookCondition
+	command    string
+	webhook    string
+	message    *template.Template
+}
+
+// hookCondition is one "field op value" or boolean term of a hook's when
+type hookCondition struct {
+	field  string
+	op     string // "==", "!=", "=~", or "" for a boolean field
+	value  string
+	negate bool // !field for boolean fields
+	re     *regexp.Regexp
+}
+
+var (
+	hookStringFields = map[string]bool{
+		"label": true, "kind": true, "state": true, "repo": true,
+		"author": true, "title": true, "gh_label": true, "milestone": true,
+	}
+	hookBoolFields = map[string]bool{"has_updates": true, "bot": true, "demoted": true}
+)
+
+// parseHookCondition parses a when expression like
+// label == "Review Requested" && has_updates && repo == minio/*
+func parseHookCondition(when string) ([]hookCondition, error) {
+	var conditions []hookCondition
+	for _, term := range strings.Split(when, "&&") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty condition in %q", when)
+		}
+
+		var c hookCondition
+		for _, op := range []string{"=~", "!=", "=="} {
+			if field, value, ok := strings.Cut(term, op); ok {
+				c = hookCondition{field: strings.TrimSpace(field), op: op, value: unquoteHookValue(strings.TrimSpace(value))}
+				break
+			}
+		}
+		if c.op == "" {
+			c.field, c.negate = strings.CutPrefix(term, "!")
+			c.field = strings.TrimSpace(c.field)
+			if !hookBoolFields[c.field] {
+				return nil, fmt.Errorf("invalid condition %q (use has_updates, bot, demoted or field == value)", term)
+			}
+			conditions = append(conditions, c)
+			continue
+		}
+
+		if !hookStringFields[c.field] {
+			return nil, fmt.Errorf("unknown field %q in %q", c.field, term)
+		}
+		if c.value == "" {
+			return nil, fmt.Errorf("missing value in %q", term)
+		}
+		switch {
+		case c.op == "=~":
+			re, err := regexp.Compile(c.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex in %q: %w", term, err)
+			}
+			c.re = re
+		case c.field == "label":
+			if !isKnownLabel(c.value) {
+				return nil, fmt.Errorf("unknown label %q in %q", c.value, term)
+			}
+		case c.field == "repo":
+			c.value = normalizeRepoPattern(c.value)
+		case c.field == "author":
+			if _, err := path.Match(c.value, ""); err != nil {
+				return nil, fmt.Errorf("invalid author pattern in %q: %w", term, err)
+			}
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// isKnownLabel reports whether label is a PR or issue feed label, ignoring case
+func isKnownLabel(label string) bool {
+	for _, labels := range []map[string]int{prLabelPriorities, issueLabelPriorities} {
+		for known := range labels {
+			if strings.EqualFold(known, label) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unquoteHookValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// compileHooks validates the hooks from config.yaml
+func compileHooks(configs []hookConfig) ([]Hook, error) {
+	var hooks []Hook
+	seen := make(map[string]bool)
+	for i, cfg := range configs {
+		name := cfg.Name
+		if name == "" {
+			name = "hooks[" + strconv.Itoa(i) + "]"
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("hooks: duplicate name %q", name)
+		}
+		seen[name] = true
+
+		if (cfg.Command == "") == (cfg.Webhook == "") {
+			return nil, fmt.Errorf("hook %s: set either command or webhook", name)
+		}
+		conditions, err := parseHookCondition(cfg.When)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", name, err)
+		}
+		message := cfg.Message
+		if message == "" {
+			message = defaultHookMessage
+		}
+		tmpl, err := template.New(name).Parse(message)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: invalid message: %w", name, err)
+		}
+		hooks = append(hooks, Hook{Name: name, conditions: conditions, command: cfg.Command, webhook: cfg.Webhook, message: tmpl})
+	}
+	return hooks, nil
+}
+
+// hookEvent is the item a hook fires for, passed as JSON to commands and to
+// message templates
+type hookEvent struct {
+	apiItem
+	Hook       string `json:"hook"`
+	HasUpdates bool   `json:"has_updates"`
+	Bot        bool   `json:"bot"`
+	Demoted    bool   `json:"demoted"`
+}
+
+func (e hookEvent) key() string {
+	return buildItemKey(e.Owner, e.Repo, e.Number)
+}
+
+func (c hookCondition) matches(e hookEvent) bool {
+	if c.op == "" {
+		value := map[string]bool{"has_updates": e.HasUpdates, "bot": e.Bot, "demoted": e.Demoted}[c.field]
+		return value != c.negate
+	}
+
+	var values []string
+	switch c.field {
+	case "label":
+		values = []string{e.Label}
+	case "kind":
+		values = []string{e.Kind}
+	case "state":
+		values = []string{e.State}
+	case "repo":
+		values = []string{e.Owner + "/" + e.Repo}
+	case "author":
+		values = []string{e.User}
+	case "title":
+		values = []string{e.Title}
+	case "gh_label":
+		values = e.GHLabels
+	case "milestone":
+		values = []string{e.Milestone}
+	}
+
+	matched := false
+	for _, value := range values {
+		switch {
+		case c.re != nil:
+			matched = c.re.MatchString(value)
+		case c.field == "repo":
+			matched = matchRepoPattern(c.value, value)
+		case c.field == "author":
+			matched, _ = path.Match(strings.ToLower(c.value), strings.ToLower(value))
+		default:
+			matched = strings.EqualFold(c.value, value)
+		}
+		if matched {
+			break
+		}
+	}
+	return matched != (c.op == "!=")
+}
+
+func (h Hook) matches(e hookEvent) bool {
+	for _, c := range h.conditions {
+		if !c.matches(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// hookEvents flattens the feed, including issues linked to PRs
+func hookEvents(sections FeedSections) []hookEvent {
+	var events []hookEvent
+	addIssue := func(issue IssueActivity) {
+		events = append(events, hookEvent{apiItem: issueAPIItem(issue), HasUpdates: issue.HasUpdates, Bot: isBot(issue.Issue.User), Demoted: issue.Demoted})
+	}
+	for _, prs := range [][]PRActivity{sections.OpenPRs, sections.ClosedPRs} {
+		for _, activity := range prs {
+			events = append(events, hookEvent{apiItem: prAPIItem(activity), HasUpdates: activity.HasUpdates, Bot: isBot(activity.PR.User), Demoted: activity.Demoted})
+			for _, issue := range activity.Issues {
+				addIssue(issue)
+			}
+		}
+	}
+	for _, issues := range [][]IssueActivity{sections.OpenIssues, sections.ClosedIssues} {
+		for _, issue := range issues {
+			addIssue(issue)
+		}
+	}
+	return events
+}
+
+// hookEnv returns the FEED_ITEM_* environment variables for a command hook
+func hookEnv(e hookEvent) []string {
+	return []string{
+		"FEED_ITEM_HOOK=" + e.Hook,
+		"FEED_ITEM_KIND=" + e.Kind,
+		"FEED_ITEM_REPO=" + e.Owner + "/" + e.Repo,
+		"FEED_ITEM_NUMBER=" + strconv.Itoa(e.Number),
+		"FEED_ITEM_TITLE=" + e.Title,
+		"FEED_ITEM_URL=" + e.URL,
+		"FEED_ITEM_LABEL=" + e.Label,
+		"FEED_ITEM_STATE=" + e.State,
+		"FEED_ITEM_AUTHOR=" + e.User,
+		"FEED_ITEM_UPDATED_AT=" + e.UpdatedAt.Format(time.RFC3339),
+		"FEED_ITEM_HAS_UPDATES=" + strconv.FormatBool(e.HasUpdates),
+	}
+}
+
+// run fires the hook for one item
+func (h Hook) run(e hookEvent) error {
+	e.Hook = h.Name
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	if h.command != "" {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		cmd := shellCommandContext(ctx, h.command)
+		cmd.Env = append(os.Environ(), hookEnv(e)...)
+		cmd.Stdin = bytes.NewReader(payload)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	var text strings.Builder
+	if err := h.message.Execute(&text, e); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func hookFiredKey(hook string, e hookEvent) []byte {
+	return []byte(hook + "|" + e.key() + "|" + e.UpdatedAt.Format(time.RFC3339))
+}
+
+// HookFired reports whether the hook already fired for this update of the item
+func (d *Database) HookFired(hook string, e hookEvent) (bool, error) {
+	fired := false
+	err := d.db.View(func(tx *bolt.Tx) error {
+		fired = tx.Bucket(hooksFiredBucket).Get(hookFiredKey(hook, e)) != nil
+		return nil
+	})
+	return fired, err
+}
+
+// RecordHookFired stores that the hook fired
+func (d *Database) RecordHookFired(hook string, e hookEvent, at time.Time) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(hooksFiredBucket).Put(hookFiredKey(hook, e), []byte(at.UTC().Format(time.RFC3339)))
+	})
+}
+
+// PruneHooksFired drops fired records older than the retention period
+func (d *Database) PruneHooksFired(now time.Time) error {
+	cutoff := now.Add(-hookFiredRetention)
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(hooksFiredBucket)
+		var expired [][]byte
+		b.ForEach(func(k, v []byte) error {
+			if firedAt, err := time.Parse(time.RFC3339, string(v)); err == nil && firedAt.Before(cutoff) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runHooks fires each matching hook once per item update. Failed hooks are
+// not recorded, so they are retried on the next fetch.
+func runHooks(db *Database, hooks []Hook, events []hookEvent) int {
+	if len(hooks) == 0 {
+		return 0
+	}
+	if db == nil || db.readOnly {
+		if config.debugMode {
+			fmt.Println("Skipping hooks: they need the database to remember what already fired")
+		}
+		return 0
+	}
+	if err := db.PruneHooksFired(time.Now()); err != nil {
+		config.dbErrorCount.Add(1)
+	}
+
+	fired := 0
+	for _, e := range events {
+		for _, hook := range hooks {
+			if !hook.matches(e) {
+				continue
+			}
+			done, err := db.HookFired(hook.Name, e)
+			if err != nil || done {
+				continue
+			}
+			if err := hook.run(e); err != nil {
+				fmt.Fprintf(config.statusOut, "Warning: hook %s failed for %s: %v\n", hook.Name, e.key(), err)
+				continue
+			}
+			if err := db.RecordHookFired(hook.Name, e, time.Now()); err != nil {
+				config.dbErrorCount.Add(1)
+			}
+			fired++
+			if config.debugMode {
+				fmt.Printf("  [Hooks] %s fired for %s\n", hook.Name, e.key())
+			}
+		}
+	}
+	return fired
+}
+
+// webhookHookEvent builds the hook event for an item a webhook delivery updated
+func webhookHookEvent(c *webhookChange) hookEvent {
+	if c.isPR {
+		return hookEvent{apiItem: prAPIItem(PRActivity{Label: c.label, Owner: c.owner, Repo: c.repo, PR: c.pr}), HasUpdates: true, Bot: isBot(c.pr.User)}
+	}
+	return hookEvent{apiItem: issueAPIItem(IssueActivity{Label: c.label, Owner: c.owner, Repo: c.repo, Issue: c.issue}), HasUpdates: true, Bot: isBot(c.issue.User)}
+}
+
+// runHooksCommand implements "github-feed hooks list" and "hooks test NAME owner/repo#N"
+func runHooksCommand(args []string) error {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "test") {
+		return errors.New("usage: github-feed hooks list | hooks test NAME owner/repo#N")
+	}
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+	hooks, err := compileHooks(s.File.Hooks)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return fmt.Errorf("no hooks defined in %s", s.Path)
+	}
+
+	if args[0] == "list" {
+		for i, hook := range hooks {
+			target := "command: " + hook.command
+			if hook.webhook != "" {
+				target = "webhook: " + hook.webhook
+			}
+			fmt.Printf("%s\n  when: %s\n  %s\n", hook.Name, s.File.Hooks[i].When, target)
+		}
+		return nil
+	}
+
+	if len(args) != 3 {
+		return errors.New("usage: github-feed hooks test NAME owner/repo#N")
+	}
+	var hook *Hook
+	for i := range hooks {
+		if hooks[i].Name == args[1] {
+			hook = &hooks[i]
+		}
+	}
+	if hook == nil {
+		return fmt.Errorf("unknown hook %q", args[1])
+	}
+	owner, repo, number, err := parseItemRef(args[2])
+	if err != nil {
+		return err
+	}
+
+	db, err := openCacheDatabaseReadOnly()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var e hookEvent
+	if pr, label, err := db.GetPullRequestWithLabel(owner, repo, number); err == nil {
+		e = hookEvent{apiItem: prAPIItem(PRActivity{Label: label, Owner: owner, Repo: repo, PR: pr}), Bot: isBot(pr.User)}
+	} else if issue, label, err := db.GetIssueWithLabel(owner, repo, number); err == nil {
+		e = hookEvent{apiItem: issueAPIItem(IssueActivity{Label: label, Owner: owner, Repo: repo, Issue: issue}), Bot: isBot(issue.User)}
+	} else {
+		return fmt.Errorf("%s is not in the cache", args[2])
+	}
+	e.HasUpdates = true
+
+	// A test ignores the conditions and the fired records
+	if !hook.matches(e) {
+		fmt.Printf("Note: %s does not match the hook's conditions; running it anyway\n", args[2])
+	}
+	if err := hook.run(e); err != nil {
+		return err
+	}
+	fmt.Printf("Ran hook %s for %s\n", hook.Name, args[2])
+	return nil
+}
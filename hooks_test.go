@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHookCondition(t *testing.T) {
+	for _, when := range []string{
+		`label == "Review Requested" && has_updates`,
+		`!bot && repo == minio/* && title =~ "(?i)security"`,
+		`gh_label == bug && state != closed && author == dependabot*`,
+	} {
+		if _, err := parseHookCondition(when); err != nil {
+			t.Errorf("parseHookCondition(%q) error = %v", when, err)
+		}
+	}
+
+	for _, when := range []string{
+		"",
+		"label == Review Request",
+		"color == red",
+		"updated",
+		"title =~ (",
+		"has_updates &&",
+	} {
+		if _, err := parseHookCondition(when); err == nil {
+			t.Errorf("parseHookCondition(%q) accepted an invalid condition", when)
+		}
+	}
+}
+
+func TestHookMatches(t *testing.T) {
+	event := hookEvent{
+		apiItem: apiItem{Kind: "pr", Owner: "minio", Repo: "minio", Number: 7, Title: "Fix security issue",
+			User: "bob", Label: "Review Requested", State: "open", GHLabels: []string{"bug", "priority"}},
+		HasUpdates: true,
+	}
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{`label == "Review Requested" && has_updates`, true},
+		{`label == 'review requested' && !has_updates`, false},
+		{`repo == minio/* && gh_label == priority`, true},
+		{`gh_label != bug`, false},
+		{`title =~ "(?i)SECURITY" && author == b*`, true},
+		{`state == merged`, false},
+		{`!bot && !demoted`, true},
+	}
+	for _, tt := range tests {
+		hooks, err := compileHooks([]hookConfig{{Name: "test", When: tt.when, Command: "true"}})
+		if err != nil {
+			t.Fatalf("compileHooks(%q) error = %v", tt.when, err)
+		}
+		if got := hooks[0].matches(event); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
+func TestCompileHooks(t *testing.T) {
+	for _, configs := range [][]hookConfig{
+		{{Name: "a", When: "has_updates"}},
+		{{Name: "a", When: "has_updates", Command: "true", Webhook: "http://localhost"}},
+		{{Name: "a", When: "has_updates", Command: "true"}, {Name: "a", When: "bot", Command: "true"}},
+		{{Name: "a", When: "has_updates", Webhook: "http://localhost", Message: "{{.Nope"}},
+	} {
+		if _, err := compileHooks(configs); err == nil {
+			t.Errorf("compileHooks(%+v) accepted an invalid hook", configs)
+		}
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&body)
+		posted = append(posted, body.Text)
+	}))
+	defer server.Close()
+
+	out := filepath.Join(t.TempDir(), "out")
+	hooks, err := compileHooks([]hookConfig{
+		{Name: "review", When: `label == "Review Requested" && has_updates`, Command: `cat > ` + out + `; echo "$FEED_ITEM_REPO#$FEED_ITEM_NUMBER" >> ` + out},
+		{Name: "chat", When: "has_updates", Webhook: server.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := config.statusOut
+	defer func() { config.statusOut = saved }()
+	config.statusOut = io.Discard
+
+	updated := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	events := []hookEvent{
+		{apiItem: apiItem{Kind: "pr", Owner: "acme", Repo: "app", Number: 1, Title: "Fix", URL: "https://github.com/acme/app/pull/1",
+			Label: "Review Requested", UpdatedAt: updated}, HasUpdates: true},
+		{apiItem: apiItem{Kind: "issue", Owner: "acme", Repo: "app", Number: 2, Label: "Mentioned", UpdatedAt: updated}},
+	}
+
+	if fired := runHooks(db, hooks, events); fired != 2 {
+		t.Errorf("runHooks() fired %d hooks, want 2", fired)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"title":"Fix"`) || !strings.HasSuffix(string(data), "acme/app#1\n") {
+		t.Errorf("command got %q, want the item JSON on stdin and FEED_ITEM_* variables", data)
+	}
+	if len(posted) != 1 || posted[0] != "Review Requested: acme/app#1 Fix\nhttps://github.com/acme/app/pull/1" {
+		t.Errorf("webhook posts = %q", posted)
+	}
+
+	// The same update never fires twice, a new one does
+	if fired := runHooks(db, hooks, events); fired != 0 {
+		t.Errorf("runHooks() fired %d hooks for an update that already fired, want 0", fired)
+	}
+	events[0].UpdatedAt = updated.Add(time.Hour)
+	if fired := runHooks(db, hooks, events); fired != 2 {
+		t.Errorf("runHooks() fired %d hooks for a new update, want 2", fired)
+	}
+
+	// Records past retention are dropped the next time hooks run
+	old := hookEvent{apiItem: apiItem{Kind: "pr", Owner: "acme", Repo: "app", Number: 9, UpdatedAt: updated}}
+	if err := db.RecordHookFired("chat", old, time.Now().Add(-hookFiredRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	runHooks(db, hooks, nil)
+	if done, err := db.HookFired("chat", old); err != nil || done {
+		t.Errorf("HookFired() for an expired record = %v, %v, want false", done, err)
+	}
+	if done, err := db.HookFired("chat", events[0]); err != nil || !done {
+		t.Errorf("HookFired() for a recent record = %v, %v, want true", done, err)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// updatedIndexBucket maps "<bucket>|<updated_at RFC3339>|<item key>" to nothing
+	updatedIndexBucket = []byte("idx_updated")
+	// repoIndexBucket maps "<bucket>|<lowercase owner/repo>|<updated_at RFC3339>|<item key>" to nothing
+	repoIndexBucket = []byte("idx_repo")
+	metaBucket      = []byte("meta")
+
+	indexVersionKey = []byte("index_version")
+)
+
+// indexVersion is bumped whenever the index key layout changes, forcing a rebuild
+const indexVersion = "2"
+
+// itemKind returns the history kind stored in bucket, or "" for non-item buckets
+func itemKind(bucket []byte) string {
+	switch {
+	case bytes.Equal(bucket, pullRequestsBucket):
+		return "PR"
+	case bytes.Equal(bucket, issuesBucket):
+		return "issue"
+	}
+	return ""
+}
+
+func updatedIndexKey(bucket []byte, updatedAt, itemKey string) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", bucket, updatedAt, itemKey))
+}
+
+// repoIndexPrefix lowercases ownerRepo since GitHub names are case-insensitive
+func repoIndexPrefix(bucket []byte, ownerRepo string) string {
+	return fmt.Sprintf("%s|%s|", bucket, strings.ToLower(ownerRepo))
+}
+
+func repoIndexKey(bucket []byte, ownerRepo, updatedAt, itemKey string) []byte {
+	return []byte(repoIndexPrefix(bucket, ownerRepo) + updatedAt + "|" + itemKey)
+}
+
+// itemKeyAfterTimestamp returns the item key that follows the "<updated_at>|" part of an index key suffix
+func itemKeyAfterTimestamp(rest []byte) (string, bool) {
+	sep := bytes.IndexByte(rest, '|')
+	if sep < 0 {
+		return "", false
+	}
+	return string(rest[sep+1:]), true
+}
+
+// indexItem moves the item's index entries from the previous snapshot's
+// updated_at to the current one
+func indexItem(tx *bolt.Tx, bucket []byte, itemKey string, previous, current map[string]string) error {
+	owner, repo, _, err := parseItemRef(itemKey)
+	if err != nil {
+		// Keys not in owner/repo#N format can't be indexed; scans still find them
+		return nil
+	}
+	ownerRepo := owner + "/" + repo
+
+	updated := tx.Bucket(updatedIndexBucket)
+	repos := tx.Bucket(repoIndexBucket)
+	if previous != nil && previous["updated_at"] != current["updated_at"] {
+		if err := updated.Delete(updatedIndexKey(bucket, previous["updated_at"], itemKey)); err != nil {
+			return err
+		}
+		if err := repos.Delete(repoIndexKey(bucket, ownerRepo, previous["updated_at"], itemKey)); err != nil {
+			return err
+		}
+	}
+	if err := updated.Put(updatedIndexKey(bucket, current["updated_at"], itemKey), nil); err != nil {
+		return err
+	}
+	return repos.Put(repoIndexKey(bucket, ownerRepo, current["updated_at"], itemKey), nil)
+}
+
+// unindexItem deletes every index entry of the item, whatever updated_at it
+// was indexed under, and returns how many there were
+func unindexItem(tx *bolt.Tx, bucket []byte, itemKey string) (int, error) {
+	prefix := []byte(string(bucket) + "|")
+	suffix := []byte("|" + itemKey)
+	removed := 0
+	for _, name := range [][]byte{updatedIndexBucket, repoIndexBucket} {
+		index := tx.Bucket(name)
+		var keys [][]byte
+		c := index.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if bytes.HasSuffix(k, suffix) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range keys {
+			if err := index.Delete(k); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ensureIndexes builds the index buckets from existing records the first time a
+// database is opened by a version that maintains them
+func (d *Database) ensureIndexes() error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if string(meta.Get(indexVersionKey)) == indexVersion {
+			return nil
+		}
+
+		for _, name := range [][]byte{updatedIndexBucket, repoIndexBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return fmt.Errorf("failed to reset index %s: %w", string(name), err)
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return fmt.Errorf("failed to create index %s: %w", string(name), err)
+			}
+		}
+
+		for _, bucket := range [][]byte{pullRequestsBucket, issuesBucket} {
+			kind := itemKind(bucket)
+			err := tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+				snapshot, err := snapshotFromRecord(kind, v)
+				if err != nil {
+					// Unreadable records are left to the scan fallback and db doctor
+					return nil
+				}
+				return indexItem(tx, bucket, string(k), nil, snapshot)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build index for %s: %w", string(bucket), err)
+			}
+		}
+
+		return meta.Put(indexVersionKey, []byte(indexVersion))
+	})
+}
+
+// isIndexed reports whether the index buckets are complete for this database
+func (d *Database) isIndexed(tx *bolt.Tx) bool {
+	meta := tx.Bucket(metaBucket)
+	return meta != nil && string(meta.Get(indexVersionKey)) == indexVersion
+}
+
+// indexedKeys returns the item keys in bucket updated at or after cutoff, limited
+// to repos when given. ok is false when the database has no usable index.
+func (d *Database) indexedKeys(tx *bolt.Tx, bucket []byte, cutoff time.Time, repos []string) (keys []string, ok bool) {
+	if !d.isIndexed(tx) {
+		return nil, false
+	}
+
+	since := cutoff.UTC().Format(time.RFC3339)
+
+	if len(repos) > 0 {
+		c := tx.Bucket(repoIndexBucket).Cursor()
+		for _, ownerRepo := range repos {
+			prefix := []byte(repoIndexPrefix(bucket, ownerRepo))
+			for k, _ := c.Seek(repoIndexKey(bucket, ownerRepo, since, "")); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				if key, ok := itemKeyAfterTimestamp(k[len(prefix):]); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+		return keys, true
+	}
+
+	prefix := []byte(string(bucket) + "|")
+	c := tx.Bucket(updatedIndexBucket).Cursor()
+	for k, _ := c.Seek(updatedIndexKey(bucket, since, "")); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if key, ok := itemKeyAfterTimestamp(k[len(prefix):]); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, true
+}
+
+// GetPullRequestsWithLabelsSince returns PRs updated at or after cutoff, limited to
+// the given owner/repo names when non-empty. It seeks the index buckets and falls
+// back to scanning every record when the database has not been indexed yet, in
+// which case callers still need to apply their own cutoff and repo filters.
+func (d *Database) GetPullRequestsWithLabelsSince(cutoff time.Time, repos []string, debugMode bool) (map[string]*github.PullRequest, map[string]string, error) {
+	prs := make(map[string]*github.PullRequest)
+	labels := make(map[string]string)
+
+	indexed := false
+	err := d.db.View(func(tx *bolt.Tx) error {
+		keys, ok := d.indexedKeys(tx, pullRequestsBucket, cutoff, repos)
+		if !ok {
+			return nil
+		}
+		indexed = true
+
+		b := tx.Bucket(pullRequestsBucket)
+		for _, key := range keys {
+			v := b.Get([]byte(key))
+			if v == nil {
+				continue
+			}
+
+			var prWithLabel PRWithLabel
+			if err := json.Unmarshal(v, &prWithLabel); err == nil && prWithLabel.PR != nil {
+				prs[key] = prWithLabel.PR
+				labels[key] = prWithLabel.Label
+				continue
+			}
+
+			var pr github.PullRequest
+			if err := json.Unmarshal(v, &pr); err != nil {
+				d.skipRecord(pullRequestsBucket, key, err, debugMode)
+				continue
+			}
+			prs[key] = &pr
+			labels[key] = "" // No label in old format
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !indexed {
+		if debugMode {
+			fmt.Printf("  [DB] No PR index available, scanning all records...\n")
+		}
+		return d.GetAllPullRequestsWithLabels(debugMode)
+	}
+
+	if debugMode {
+		fmt.Printf("  [DB] Loaded %d PRs from index\n", len(prs))
+	}
+	return prs, labels, nil
+}
+
+// GetIssuesWithLabelsSince is the issue counterpart of GetPullRequestsWithLabelsSince
+func (d *Database) GetIssuesWithLabelsSince(cutoff time.Time, repos []string, debugMode bool) (map[string]*github.Issue, map[string]string, error) {
+	issues := make(map[string]*github.Issue)
+	labels := make(map[string]string)
+
+	indexed := false
+	err := d.db.View(func(tx *bolt.Tx) error {
+		keys, ok := d.indexedKeys(tx, issuesBucket, cutoff, repos)
+		if !ok {
+			return nil
+		}
+		indexed = true
+
+		b := tx.Bucket(issuesBucket)
+		for _, key := range keys {
+			v := b.Get([]byte(key))
+			if v == nil {
+				continue
+			}
+
+			var issueWithLabel IssueWithLabel
+			if err := json.Unmarshal(v, &issueWithLabel); err == nil && issueWithLabel.Issue != nil {
+				issues[key] = issueWithLabel.Issue
+				labels[key] = issueWithLabel.Label
+				continue
+			}
+
+			var issue github.Issue
+			if err := json.Unmarshal(v, &issue); err != nil {
+				d.skipRecord(issuesBucket, key, err, debugMode)
+				continue
+			}
+			issues[key] = &issue
+			labels[key] = "" // No label in old format
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !indexed {
+		if debugMode {
+			fmt.Printf("  [DB] No issue index available, scanning all records...\n")
+		}
+		return d.GetAllIssuesWithLabels(debugMode)
+	}
+
+	if debugMode {
+		fmt.Printf("  [DB] Loaded %d issues from index\n", len(issues))
+	}
+	return issues, labels, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestIndexedLookups(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	save := func(repo string, number int, updated time.Time) {
+		pr := &github.PullRequest{
+			Number:    github.Int(number),
+			UpdatedAt: &github.Timestamp{Time: updated},
+		}
+		if err := db.SavePullRequestWithLabel("owner", repo, pr, "Authored", false); err != nil {
+			t.Fatalf("SavePullRequestWithLabel() error = %v", err)
+		}
+	}
+
+	save("a", 1, now.Add(-48*time.Hour))
+	save("a", 2, now.Add(-1*time.Hour))
+	save("b", 3, now.Add(-2*time.Hour))
+	// Moving an item forward in time must drop its old index entries
+	save("a", 1, now)
+
+	cutoff := now.Add(-24 * time.Hour)
+
+	prs, _, err := db.GetPullRequestsWithLabelsSince(cutoff, nil, false)
+	if err != nil {
+		t.Fatalf("GetPullRequestsWithLabelsSince() error = %v", err)
+	}
+	if len(prs) != 3 {
+		t.Errorf("GetPullRequestsWithLabelsSince(all repos) returned %d PRs, want 3", len(prs))
+	}
+
+	prs, _, err = db.GetPullRequestsWithLabelsSince(cutoff, []string{"owner/a"}, false)
+	if err != nil {
+		t.Fatalf("GetPullRequestsWithLabelsSince() error = %v", err)
+	}
+	if len(prs) != 2 || prs["owner/a#1"] == nil || prs["owner/a#2"] == nil {
+		t.Errorf("GetPullRequestsWithLabelsSince(owner/a) = %v, want owner/a#1 and owner/a#2", prs)
+	}
+
+	prs, _, err = db.GetPullRequestsWithLabelsSince(now.Add(-90*time.Minute), []string{"owner/a", "owner/b"}, false)
+	if err != nil {
+		t.Fatalf("GetPullRequestsWithLabelsSince() error = %v", err)
+	}
+	if len(prs) != 2 || prs["owner/b#3"] != nil {
+		t.Errorf("GetPullRequestsWithLabelsSince(90m) = %v, want owner/a#1 and owner/a#2", prs)
+	}
+
+	// Repo names typed with different casing must still hit the index
+	prs, _, err = db.GetPullRequestsWithLabelsSince(cutoff, []string{"Owner/B"}, false)
+	if err != nil {
+		t.Fatalf("GetPullRequestsWithLabelsSince() error = %v", err)
+	}
+	if len(prs) != 1 || prs["owner/b#3"] == nil {
+		t.Errorf("GetPullRequestsWithLabelsSince(Owner/B) = %v, want owner/b#3", prs)
+	}
+}
@@ -1,20 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"hash/fnv"
+	"io"
 	"math"
+	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/fatih/color"
@@ -28,6 +28,7 @@ type PRActivity struct {
 	PR         *github.PullRequest
 	UpdatedAt  time.Time
 	HasUpdates bool
+	Demoted    bool // matched a demote rule, shown last
 	Issues     []IssueActivity
 }
 
@@ -38,9 +39,11 @@ type IssueActivity struct {
 	Issue      *github.Issue
 	UpdatedAt  time.Time
 	HasUpdates bool
+	Demoted    bool // matched a demote rule, shown last
 }
 
 type Progress struct {
+	hidden  bool // statusOut is not a terminal
 	current atomic.Int32
 	total   atomic.Int32
 }
@@ -49,41 +52,62 @@ type Config struct {
 	debugMode     bool
 	localMode     bool
 	showLinks     bool
-	timeRange     time.Duration
+	window        TimeWindow
 	username      string
-	allowedRepos  map[string]bool
+	repoFilter    RepoFilter
+	itemFilter    ItemFilter
+	rules         []Rule
+	showHidden    bool
+	hiddenCount   int
+	outputFormat  string
+	groupBy       string
+	sortField     string
+	sortReverse   bool
+	termWidth     int  // truncate titles to this width, 0 for no limit
+	hyperlinks    bool // emit OSC 8 links on item refs
+	relativeDates bool
+	outputPath    string
+	itemTemplate  *template.Template // --template, replaces the default item line
+	statusOut     io.Writer          // progress and warnings, kept off stdout when a report is written there
 	client        *github.Client
 	db            *Database
 	progress      *Progress
 	ctx           context.Context
 	dbErrorCount  atomic.Int32
+	updateMarks   []string // items a webhook marked as updated, cleared once shown
+	hooks         []Hook
 }
 
-var config Config
+var config = Config{statusOut: os.Stdout}
+
+// prLabelPriorities ranks feed labels for PRs, lowest first; config.yaml can
+// override them under label-priority.pr
+var prLabelPriorities = map[string]int{
+	"Authored":         1,
+	"Assigned":         2,
+	"Reviewed":         3,
+	"Review Requested": 4,
+	"Commented":        5,
+	"Mentioned":        6,
+}
+
+// issueLabelPriorities is the issue counterpart of prLabelPriorities
+var issueLabelPriorities = map[string]int{
+	"Authored":  1,
+	"Assigned":  2,
+	"Commented": 3,
+	"Mentioned": 4,
+}
 
 func getPRLabelPriority(label string) int {
-	priorities := map[string]int{
-		"Authored":         1,
-		"Assigned":         2,
-		"Reviewed":         3,
-		"Review Requested": 4,
-		"Commented":        5,
-		"Mentioned":        6,
-	}
-	if priority, ok := priorities[label]; ok {
+	if priority, ok := prLabelPriorities[label]; ok {
 		return priority
 	}
 	return 999 // Unknown labels get lowest priority
 }
 
 func getIssueLabelPriority(label string) int {
-	priorities := map[string]int{
-		"Authored":  1,
-		"Assigned":  2,
-		"Commented": 3,
-		"Mentioned": 4,
-	}
-	if priority, ok := priorities[label]; ok {
+	if priority, ok := issueLabelPriorities[label]; ok {
 		return priority
 	}
 	return 999 // Unknown labels get lowest priority
@@ -139,10 +163,13 @@ func (p *Progress) buildBar(current, total int32) (string, *color.Color, float64
 }
 
 func (p *Progress) display() {
+	if p.hidden {
+		return
+	}
 	current := p.current.Load()
 	total := p.total.Load()
 	barContent, barColor, percentage := p.buildBar(current, total)
-	fmt.Printf("\r[%s] %s/%s (%s) ",
+	fmt.Fprintf(config.statusOut, "\r[%s] %s/%s (%s) ",
 		barColor.Sprint(barContent),
 		color.New(color.FgCyan).Sprint(current),
 		color.New(color.FgCyan).Sprint(total),
@@ -150,10 +177,13 @@ func (p *Progress) display() {
 }
 
 func (p *Progress) displayWithWarning(message string) {
+	if p.hidden {
+		return
+	}
 	current := p.current.Load()
 	total := p.total.Load()
 	barContent, barColor, percentage := p.buildBar(current, total)
-	fmt.Printf("\r[%s] %s/%s (%s) %s ",
+	fmt.Fprintf(config.statusOut, "\r[%s] %s/%s (%s) %s ",
 		barColor.Sprint(barContent),
 		color.New(color.FgCyan).Sprint(current),
 		color.New(color.FgCyan).Sprint(total),
@@ -236,6 +266,7 @@ func retryWithBackoff(operation func() error, operationName string) error {
 			}
 		}
 
+		metrics.countRetry(isRateLimitError)
 		if isRateLimitError {
 			if config.debugMode {
 				select {
@@ -301,22 +332,28 @@ func retryWithBackoff(operation func() error, operationName string) error {
 	}
 }
 
-func getLabelColor(label string) *color.Color {
-	labelColors := map[string]*color.Color{
-		"Authored":         color.New(color.FgCyan),
-		"Mentioned":        color.New(color.FgYellow),
-		"Assigned":         color.New(color.FgMagenta),
-		"Commented":        color.New(color.FgBlue),
-		"Reviewed":         color.New(color.FgGreen),
-		"Review Requested": color.New(color.FgRed),
-		"Involved":         color.New(color.FgHiBlack),
-		"Recent Activity":  color.New(color.FgHiCyan),
-	}
+// labelColorAttributes maps feed labels to their terminal color, shared by the
+// terminal display and the HTML report
+var labelColorAttributes = map[string]color.Attribute{
+	"Authored":         color.FgCyan,
+	"Mentioned":        color.FgYellow,
+	"Assigned":         color.FgMagenta,
+	"Commented":        color.FgBlue,
+	"Reviewed":         color.FgGreen,
+	"Review Requested": color.FgRed,
+	"Involved":         color.FgHiBlack,
+	"Recent Activity":  color.FgHiCyan,
+}
 
-	if c, ok := labelColors[label]; ok {
-		return c
+func getLabelAttribute(label string) color.Attribute {
+	if attr, ok := labelColorAttributes[label]; ok {
+		return attr
 	}
-	return color.New(color.FgWhite)
+	return color.FgWhite
+}
+
+func getLabelColor(label string) *color.Color {
+	return color.New(getLabelAttribute(label))
 }
 
 func getUserColor(username string) *color.Color {
@@ -341,133 +378,153 @@ func getUserColor(username string) *color.Color {
 	return colors[hash%uint32(len(colors))]
 }
 
-func getStateColor(state string) *color.Color {
+func getStateAttribute(state string) color.Attribute {
 	switch state {
 	case "open":
-		return color.New(color.FgGreen)
+		return color.FgGreen
 	case "closed":
-		return color.New(color.FgRed)
+		return color.FgRed
 	case "merged":
-		return color.New(color.FgMagenta)
+		return color.FgMagenta
 	default:
-		return color.New(color.FgWhite)
+		return color.FgWhite
 	}
 }
 
-func loadEnvFile(path string) error {
-	file, err := os.Open(path)
+func getStateColor(state string) *color.Color {
+	return color.New(getStateAttribute(state))
+}
+
+// getConfigDir returns ~/.github-feed, creating it if needed
+func getConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", fmt.Errorf("could not determine home directory: %w", err)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			os.Setenv(key, value)
-		}
+	configDir := filepath.Join(homeDir, ".github-feed")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return "", fmt.Errorf("could not create config directory %s: %w", configDir, err)
 	}
-
-	return scanner.Err()
+	return configDir, nil
 }
 
-func parseTimeRange(timeStr string) (time.Duration, error) {
-	if len(timeStr) < 2 {
-		return 0, fmt.Errorf("invalid time range format: %s (expected format like 1h, 2d, 3w, 4m, 1y)", timeStr)
-	}
-
-	numStr := timeStr[:len(timeStr)-1]
-	unit := timeStr[len(timeStr)-1:]
+// cliOptions holds the values of the main command's flags
+type cliOptions struct {
+	timeRangeStr       string
+	sinceFlag          string
+	untilFlag          string
+	debugMode          bool
+	localMode          bool
+	showLinks          bool
+	llMode             bool
+	allowedReposFlag   string
+	excludedReposFlag  string
+	orgFlag            string
+	labelFlag          string
+	ghLabelFlag        string
+	excludeGHLabelFlag string
+	milestoneFlag      string
+	authorFlag         string
+	notAuthorFlag      string
+	cleanCache         bool
+	dbWait             time.Duration
+	outputFormat       string
+	groupBy            string
+	relativeDates      bool
+	showHidden         bool
+	sortFlag           string
+	outputPath         string
+	templateFlag       string
+}
 
-	num, err := strconv.Atoi(numStr)
-	if err != nil || num < 1 {
-		return 0, fmt.Errorf("invalid time range number: %s (must be a positive integer)", numStr)
-	}
+// registerFlags defines the main command's flags on fs. config show registers
+// them on its own flag set to report their defaults.
+func registerFlags(fs *flag.FlagSet) *cliOptions {
+	o := &cliOptions{}
+	fs.StringVar(&o.timeRangeStr, "time", "1m", "Show items from last time range (1h, 2d, 3w, 4m, 1y, combined like 1w3d, or today, yesterday, this-week, last-week, this-month, last-month, this-sprint, last-sprint)")
+	fs.StringVar(&o.sinceFlag, "since", "", "Show items updated on or after this date (YYYY-MM-DD), instead of --time")
+	fs.StringVar(&o.untilFlag, "until", "", "Show items updated on or before this date (YYYY-MM-DD)")
+	fs.BoolVar(&o.debugMode, "debug", false, "Show detailed API logging")
+	fs.BoolVar(&o.localMode, "local", false, "Use local database instead of GitHub API")
+	fs.BoolVar(&o.showLinks, "links", false, "Show hyperlinks underneath each PR/issue")
+	fs.BoolVar(&o.llMode, "ll", false, "Shortcut for --local --links (offline mode with links)")
+	fs.BoolVar(&o.relativeDates, "relative", false, "Show relative ages (3h ago) instead of dates")
+	fs.BoolVar(&o.showHidden, "show-hidden", false, "Show items hidden by the rules file (listed last, dimmed)")
+	fs.BoolVar(&o.cleanCache, "clean", false, "Delete and recreate the database cache")
+	fs.DurationVar(&o.dbWait, "db-wait", 10*time.Second, "How long to wait for another instance to release the database")
+	fs.StringVar(&o.outputFormat, "format", "text", "Output format: text, markdown, html, csv or tsv")
+	fs.StringVar(&o.groupBy, "group-by", "state", "Group items by state, repo, label, author or none")
+	fs.StringVar(&o.sortFlag, "sort", "updated", "Sort by updated, created, number, repo or label-priority (prefix with - to reverse)")
+	fs.StringVar(&o.outputPath, "out", "", "Write the feed to this file instead of stdout")
+	fs.StringVar(&o.templateFlag, "template", "", "Go template for each item line, or a template name (compact, wide, or ~/.github-feed/templates/NAME.tmpl)")
+	fs.StringVar(&o.allowedReposFlag, "allowed-repos", "", "Comma-separated list of allowed repos or patterns (e.g., user/repo1,minio/*,*/docs)")
+	fs.StringVar(&o.excludedReposFlag, "exclude-repos", "", "Comma-separated list of repos or patterns to hide (e.g., minio/mint,*/website)")
+	fs.StringVar(&o.orgFlag, "org", "", "Comma-separated list of organizations to limit results to")
+	fs.StringVar(&o.labelFlag, "label", "", "Only show items with any of these feed labels, e.g. \"Review Requested,Reviewed\" (comma-separated)")
+	fs.StringVar(&o.ghLabelFlag, "gh-label", "", "Only show items with any of these GitHub labels (comma-separated)")
+	fs.StringVar(&o.excludeGHLabelFlag, "exclude-gh-label", "", "Hide items with any of these GitHub labels (comma-separated)")
+	fs.StringVar(&o.milestoneFlag, "milestone", "", "Only show items in this milestone")
+	fs.StringVar(&o.authorFlag, "author", "", "Only show items opened by these users (comma-separated)")
+	fs.StringVar(&o.notAuthorFlag, "not-author", "", "Hide items opened by these users, e.g. dependabot[bot] (comma-separated)")
+	return o
+}
 
-	var duration time.Duration
-	switch unit {
-	case "h":
-		duration = time.Duration(num) * time.Hour
-	case "d":
-		duration = time.Duration(num) * 24 * time.Hour
-	case "w":
-		duration = time.Duration(num) * 7 * 24 * time.Hour
-	case "m":
-		duration = time.Duration(num) * 30 * 24 * time.Hour
-	case "y":
-		duration = time.Duration(num) * 365 * 24 * time.Hour
-	default:
-		return 0, fmt.Errorf("invalid time unit: %s (use h=hours, d=days, w=weeks, m=months, y=years)", unit)
+func main() {
+	// Dispatch subcommands (e.g. "github-feed history owner/repo#1")
+	if len(os.Args) > 1 {
+		if cmd, ok := findSubcommand(os.Args[1]); ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	return duration, nil
+	runFeed(os.Args[1:])
 }
 
-func main() {
-	// Define flags
-	var timeRangeStr string
-	var debugMode bool
-	var localMode bool
-	var showLinks bool
-	var llMode bool
-	var allowedReposFlag string
-	var cleanCache bool
-
-	flag.StringVar(&timeRangeStr, "time", "1m", "Show items from last time range (1h, 2d, 3w, 4m, 1y)")
-	flag.BoolVar(&debugMode, "debug", false, "Show detailed API logging")
-	flag.BoolVar(&localMode, "local", false, "Use local database instead of GitHub API")
-	flag.BoolVar(&showLinks, "links", false, "Show hyperlinks underneath each PR/issue")
-	flag.BoolVar(&llMode, "ll", false, "Shortcut for --local --links (offline mode with links)")
-	flag.BoolVar(&cleanCache, "clean", false, "Delete and recreate the database cache")
-	flag.StringVar(&allowedReposFlag, "allowed-repos", "", "Comma-separated list of allowed repos (e.g., user/repo1,user/repo2)")
+// runFeed shows the feed for the main command's flags in args
+func runFeed(args []string) {
+	opts := registerFlags(flag.CommandLine)
 
 	// Custom usage message
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s <command> [arguments]\n\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "GitHub Feed - Monitor GitHub pull requests and issues across repositories")
 		fmt.Fprintln(os.Stderr, "\nOptions:")
 		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "\nCommands:")
+		printSubcommands()
 		fmt.Fprintln(os.Stderr, "\nEnvironment Variables:")
 		fmt.Fprintln(os.Stderr, "  GITHUB_TOKEN or GITHUB_ACTIVITY_TOKEN - GitHub Personal Access Token")
 		fmt.Fprintln(os.Stderr, "  GITHUB_USERNAME or GITHUB_USER         - Your GitHub username")
-		fmt.Fprintln(os.Stderr, "  ALLOWED_REPOS                          - Comma-separated list of allowed repos")
-		fmt.Fprintln(os.Stderr, "\nConfiguration File:")
-		fmt.Fprintln(os.Stderr, "  ~/.github-feed/.env                    - Configuration file (auto-created)")
+		fmt.Fprintln(os.Stderr, "  ALLOWED_REPOS, EXCLUDED_REPOS          - Comma-separated lists of repos or patterns")
+		fmt.Fprintln(os.Stderr, "  GITHUB_FEED_<OPTION>                   - Any option, e.g. GITHUB_FEED_GROUP_BY=repo")
+		fmt.Fprintln(os.Stderr, "\nConfiguration Files:")
+		fmt.Fprintln(os.Stderr, "  ~/.github-feed/config.yaml             - Options, label priorities, colors and views")
+		fmt.Fprintln(os.Stderr, "  ~/.github-feed/.env                    - Credentials (auto-created)")
+		fmt.Fprintln(os.Stderr, "\nPrecedence: flag > environment > .env > config.yaml > default (see 'config show')")
 	}
 
-	flag.Parse()
+	flag.CommandLine.Parse(args)
 
-	// Handle --ll shortcut
-	if llMode {
-		localMode = true
-		showLinks = true
+	if db := setupFeed(flag.CommandLine, opts); db != nil {
+		db.holdLock()
+		defer db.Close()
 	}
+	fetchAndDisplayActivity()
+}
 
-	// Parse time range
-	timeRange, err := parseTimeRange(timeRangeStr)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		fmt.Println("Examples: --time 1h (1 hour), --time 2d (2 days), --time 3w (3 weeks), --time 4m (4 months), --time 1y (1 year)")
-		os.Exit(1)
-	}
+// setupFeed resolves settings for the parsed flags in fs and fills in config,
+// exiting on configuration errors. It returns the open database, if any.
+func setupFeed(fs *flag.FlagSet, opts *cliOptions) *Database {
 
-	homeDir, err := os.UserHomeDir()
+	configDir, err := getConfigDir()
 	if err != nil {
-		fmt.Printf("Error: Could not determine home directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	configDir := filepath.Join(homeDir, ".github-feed")
-	if err := os.MkdirAll(configDir, 0o755); err != nil {
-		fmt.Printf("Error: Could not create config directory %s: %v\n", configDir, err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -487,93 +544,188 @@ GITHUB_USERNAME=
 # Optional: Comma-separated list of allowed repos (e.g., user/repo1,user/repo2)
 # Leave empty to allow all repos
 ALLOWED_REPOS=
+
+# Other options, label priorities, colors and views go in config.yaml
+# Run "github-feed config show" to see the effective configuration
 `
 		if err := os.WriteFile(envPath, []byte(envTemplate), 0o600); err != nil {
-			fmt.Printf("Warning: Could not create .env file at %s: %v\n", envPath, err)
+			fmt.Fprintf(config.statusOut, "Warning: Could not create .env file at %s: %v\n", envPath, err)
 		}
 	}
 
-	_ = loadEnvFile(envPath)
+	// Flags not given on the command line take their value from the
+	// environment, the legacy .env file or config.yaml, in that order
+	settings, err = loadSettings(fs, configDir)
+	if err == nil {
+		err = settings.applyLabelSettings()
+	}
+	var hooks []Hook
+	if err == nil {
+		hooks, err = compileHooks(settings.File.Hooks)
+	}
+	if err != nil {
+		fmt.Printf("Error: invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
 
-	username := os.Getenv("GITHUB_USERNAME")
-	if username == "" {
-		username = os.Getenv("GITHUB_USER")
+	// Handle --ll shortcut
+	if opts.llMode {
+		opts.localMode = true
+		opts.showLinks = true
 	}
 
-	allowedReposStr := allowedReposFlag
-	if allowedReposStr == "" {
-		allowedReposStr = os.Getenv("ALLOWED_REPOS")
+	if !isValidOutputFormat(opts.outputFormat) {
+		fmt.Printf("Error: invalid --format %q (use %s)\n", opts.outputFormat, strings.Join(outputFormats, ", "))
+		os.Exit(1)
+	}
+	// Keep progress and warnings off stdout when a report is written there.
+	// Commands that print something else on stdout set statusOut beforehand.
+	if opts.outputFormat != "text" && opts.outputPath == "" {
+		config.statusOut = os.Stderr
+	}
+	if !isValidGroupBy(opts.groupBy) {
+		fmt.Printf("Error: invalid --group-by %q (use %s)\n", opts.groupBy, strings.Join(groupByModes, ", "))
+		os.Exit(1)
+	}
+	sortField, sortReverse, err := parseSortFlag(opts.sortFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	var allowedRepos map[string]bool
-	if allowedReposStr != "" {
-		allowedRepos = make(map[string]bool)
-		repos := strings.Split(allowedReposStr, ",")
-		for _, repo := range repos {
-			repo = strings.TrimSpace(repo)
-			if repo != "" {
-				allowedRepos[repo] = true
-			}
-		}
-		if debugMode && len(allowedRepos) > 0 {
-			fmt.Printf("Filtering to allowed repositories: %v\n", allowedRepos)
-		}
+	// Parse the time window after settings so sprint-days and sprint-start apply
+	window, err := parseTimeWindow(opts.timeRangeStr, opts.sinceFlag, opts.untilFlag, time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Examples: --time 1h, --time 1w3d, --time 4m, --time yesterday, --time last-sprint, --since 2026-09-01 --until 2026-09-30")
+		os.Exit(1)
+	}
+
+	username := settingValue("username")
+
+	rules, err := loadRules(filepath.Join(configDir, rulesFileName))
+	if err != nil {
+		fmt.Printf("Error: invalid rules file: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.debugMode && len(rules) > 0 {
+		fmt.Printf("Loaded %d suppression rule(s)\n", len(rules))
+	}
+
+	repoFilter := newRepoFilter(opts.allowedReposFlag, opts.excludedReposFlag, opts.orgFlag)
+	if opts.debugMode && !repoFilter.isEmpty() {
+		fmt.Printf("Filtering repositories: allowed %v, excluded %v, orgs %v\n", repoFilter.include, repoFilter.exclude, repoFilter.orgs)
 	}
 
 	dbPath := filepath.Join(configDir, "github.db")
 
-	if cleanCache {
-		fmt.Println("Cleaning database cache...")
+	if opts.cleanCache {
+		fmt.Fprintln(config.statusOut, "Cleaning database cache...")
 		if _, err := os.Stat(dbPath); err == nil {
 			if err := os.Remove(dbPath); err != nil {
-				fmt.Printf("Warning: Failed to delete database file: %v\n", err)
+				fmt.Fprintf(config.statusOut, "Warning: Failed to delete database file: %v\n", err)
 			} else {
-				fmt.Println("Database cache cleaned successfully")
+				fmt.Fprintln(config.statusOut, "Database cache cleaned successfully")
 			}
 		} else {
-			fmt.Println("No existing database cache to clean")
+			fmt.Fprintln(config.statusOut, "No existing database cache to clean")
 		}
 	}
 
-	db, err := OpenDatabase(dbPath)
+	// --local only reads, so it can run next to another instance that is writing
+	var db *Database
+	if opts.localMode {
+		db, err = OpenDatabaseReadOnly(dbPath, opts.debugMode)
+	} else {
+		db, err = OpenDatabaseWait(dbPath, opts.dbWait, opts.debugMode)
+	}
 	if err != nil {
-		fmt.Printf("Warning: Failed to open database: %v\n", err)
-		fmt.Println("Continuing without database caching...")
+		var locked *ErrDatabaseLocked
+		if errors.As(err, &locked) {
+			fmt.Fprintf(config.statusOut, "Warning: %v\n", err)
+		} else {
+			fmt.Fprintf(config.statusOut, "Warning: Failed to open database: %v\n", err)
+		}
+		fmt.Fprintln(config.statusOut, "Continuing without database caching...")
 		db = nil
-	} else {
-		defer db.Close()
 	}
 
-	token := os.Getenv("GITHUB_ACTIVITY_TOKEN")
-	if token == "" {
-		token = os.Getenv("GITHUB_TOKEN")
+	// --local never talks to GitHub, so don't run token commands or keyring lookups
+	var token, tokenDetail string
+	var app *appTokenSource
+	if !opts.localMode {
+		config.debugMode = opts.debugMode
+		app, err = newAppTokenSource()
+		if err == nil && app != nil {
+			// Mint the first installation token now so bad credentials fail early
+			token, err = app.Token(context.Background())
+			tokenDetail = fmt.Sprintf("GitHub App %d, installation %d", app.appID, app.installationID)
+		} else if err == nil {
+			token, tokenDetail, err = resolveToken()
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Validate configuration
-	if err := validateConfig(username, token, localMode, envPath); err != nil {
+	if err := validateConfig(username, token, opts.localMode, envPath); err != nil {
 		fmt.Printf("Configuration Error: %v\n\n", err)
 		os.Exit(1)
 	}
+	if token != "" {
+		if warning := tokenFormatWarning(token); warning != "" {
+			fmt.Fprintf(config.statusOut, "Warning: %s\n", warning)
+		}
+		if opts.debugMode {
+			fmt.Printf("Using GitHub token from %s\n", tokenDetail)
+		}
+	}
 
-	if debugMode {
+	if opts.debugMode {
 		fmt.Printf("Monitoring GitHub PR activity for user: %s\n", username)
-		fmt.Printf("Showing items from the last %v\n", timeRange)
+		fmt.Printf("Showing items updated %s\n", window)
 	}
-	if debugMode {
+	if opts.debugMode {
 		fmt.Println("Debug mode enabled")
 	}
 
-	config.debugMode = debugMode
-	config.localMode = localMode
-	config.showLinks = showLinks
-	config.timeRange = timeRange
+	config.debugMode = opts.debugMode
+	config.localMode = opts.localMode
+	config.showLinks = opts.showLinks
+	config.window = window
 	config.username = username
-	config.allowedRepos = allowedRepos
+	config.repoFilter = repoFilter
+	config.rules = rules
+	config.hooks = hooks
+	config.showHidden = opts.showHidden
+	config.itemFilter = newItemFilter(opts.ghLabelFlag, opts.excludeGHLabelFlag, opts.milestoneFlag, opts.authorFlag, opts.notAuthorFlag, opts.labelFlag)
+	if opts.templateFlag != "" {
+		tmpl, err := loadItemTemplate(opts.templateFlag, configDir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		config.itemTemplate = tmpl
+	}
+
+	config.outputFormat = opts.outputFormat
+	config.groupBy = opts.groupBy
+	config.sortField = sortField
+	config.sortReverse = sortReverse
+	config.relativeDates = opts.relativeDates
+	config.termWidth = terminalWidth()
+	config.hyperlinks = supportsHyperlinks()
+	config.outputPath = opts.outputPath
 	config.db = db
 	config.ctx = context.Background()
-	config.client = github.NewClient(nil).WithAuthToken(token)
-
-	fetchAndDisplayActivity()
+	if app != nil {
+		config.client = app.client()
+	} else {
+		config.client = github.NewClient(&http.Client{Transport: &metricsTransport{base: http.DefaultTransport}}).WithAuthToken(token)
+	}
+	return db
 }
 
 func validateConfig(username, token string, localMode bool, envPath string) error {
@@ -586,25 +738,20 @@ func validateConfig(username, token string, localMode bool, envPath string) erro
 	}
 
 	if token == "" {
-		return fmt.Errorf("GitHub token is required.\n\nTo fix this:\n  1. Generate a token at https://github.com/settings/tokens\n  2. Click 'Generate new token' -> 'Generate new token (classic)'\n  3. Give it a name and select scopes: 'repo', 'read:org'\n  4. Generate and copy the token\n  5. Set GITHUB_TOKEN environment variable\n  6. Or add it to %s", envPath)
-	}
-
-	// Validate token format (GitHub PAT tokens start with ghp_, gho_, or github_pat_)
-	if !strings.HasPrefix(token, "ghp_") &&
-		!strings.HasPrefix(token, "gho_") &&
-		!strings.HasPrefix(token, "github_pat_") {
-		return fmt.Errorf("GitHub token format looks invalid.\n\nGitHub Personal Access Tokens should start with:\n  - 'ghp_' (classic PAT)\n  - 'gho_' (OAuth token)\n  - 'github_pat_' (fine-grained PAT)\n\nYour token starts with: '%s'\n\nPlease check your token at https://github.com/settings/tokens", token[:min(10, len(token))])
+		return fmt.Errorf("GitHub token is required.\n\nTo fix this:\n  1. Generate a token at https://github.com/settings/tokens\n  2. Click 'Generate new token' -> 'Generate new token (classic)'\n  3. Give it a name and select scopes: 'repo', 'read:org'\n  4. Generate and copy the token\n  5. Set GITHUB_TOKEN environment variable\n  6. Or add it to %s\n\nThe token can also come from the keyring, the gh CLI or GITHUB_TOKEN_COMMAND; run 'github-feed auth status' to check.", envPath)
 	}
 
 	return nil
 }
 
 func isRepoAllowed(owner, repo string) bool {
-	if config.allowedRepos == nil || len(config.allowedRepos) == 0 {
-		return true
-	}
-	repoKey := fmt.Sprintf("%s/%s", owner, repo)
-	return config.allowedRepos[repoKey]
+	return config.repoFilter.allows(owner, repo)
+}
+
+// allowedRepoList returns the allowed owner/repo names lowercased and sorted, or nil when
+// the allowed set is not a plain list of repos
+func allowedRepoList() []string {
+	return config.repoFilter.exactRepos()
 }
 
 func checkRateLimit() error {
@@ -622,50 +769,101 @@ func checkRateLimit() error {
 
 	core := rateLimits.Core
 	search := rateLimits.Search
+	metrics.setRateLimit("core", core)
+	metrics.setRateLimit("search", search)
 
 	if config.debugMode {
-		fmt.Printf("Rate Limits - Core: %d/%d, Search: %d/%d\n",
+		fmt.Fprintf(config.statusOut, "Rate Limits - Core: %d/%d, Search: %d/%d\n",
 			core.Remaining, core.Limit,
 			search.Remaining, search.Limit)
 	}
 
 	if core.Remaining == 0 {
 		resetTime := core.Reset.Time.Sub(time.Now())
-		fmt.Printf("WARNING: Core API rate limit exceeded! Resets in %v\n", resetTime.Round(time.Second))
+		fmt.Fprintf(config.statusOut, "WARNING: Core API rate limit exceeded! Resets in %v\n", resetTime.Round(time.Second))
 		return fmt.Errorf("rate limit exceeded, resets at %v", core.Reset.Time.Format("15:04:05"))
 	}
 
 	if search.Remaining == 0 {
 		resetTime := search.Reset.Time.Sub(time.Now())
-		fmt.Printf("WARNING: Search API rate limit exceeded! Resets in %v\n", resetTime.Round(time.Second))
+		fmt.Fprintf(config.statusOut, "WARNING: Search API rate limit exceeded! Resets in %v\n", resetTime.Round(time.Second))
 		return fmt.Errorf("search rate limit exceeded, resets at %v", search.Reset.Time.Format("15:04:05"))
 	}
 
 	coreThreshold := core.Limit / 5
 	if core.Remaining < coreThreshold && core.Remaining > 0 {
-		fmt.Printf("WARNING: Core API rate limit running low (%d remaining)\n", core.Remaining)
+		fmt.Fprintf(config.statusOut, "WARNING: Core API rate limit running low (%d remaining)\n", core.Remaining)
 	}
 
 	if search.Remaining < 5 && search.Remaining > 0 {
-		fmt.Printf("WARNING: Search API rate limit running low (%d remaining)\n", search.Remaining)
+		fmt.Fprintf(config.statusOut, "WARNING: Search API rate limit running low (%d remaining)\n", search.Remaining)
 	}
 
 	return nil
 }
 
 func fetchAndDisplayActivity() {
+	sections, ok := fetchActivity()
+	if !ok {
+		return
+	}
+	if err := renderFeed(sections); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	} else if config.db != nil {
+		if err := config.db.ClearUpdateMarks(config.updateMarks); err != nil && config.debugMode {
+			fmt.Printf("  [DB] Warning: Failed to clear update marks: %v\n", err)
+		}
+	}
+
+	if config.hiddenCount > 0 && !config.showHidden {
+		fmt.Fprintf(config.statusOut, "\n%d item(s) hidden by suppression rules in ~/.github-feed/%s (use --show-hidden to see them)\n",
+			config.hiddenCount, rulesFileName)
+	}
+
+	// Warn about database errors if any occurred
+	if dbErrors := config.dbErrorCount.Load(); dbErrors > 0 {
+		fmt.Fprintf(config.statusOut, "\n")
+		warningColor := color.New(color.FgYellow, color.Bold)
+		fmt.Fprintf(config.statusOut, "%s %d database write error(s) occurred. Offline mode may be incomplete.\n",
+			warningColor.Sprint("Warning:"), dbErrors)
+		if !config.debugMode {
+			fmt.Fprintln(config.statusOut, "Run with --debug to see detailed error messages.")
+		}
+	}
+
+	// Warn about cached records that could not be read
+	if config.db != nil {
+		if skipped := config.db.SkippedRecordCount(); skipped > 0 {
+			fmt.Fprintf(config.statusOut, "\n")
+			warningColor := color.New(color.FgYellow, color.Bold)
+			fmt.Fprintf(config.statusOut, "%s %d unreadable database record(s) were skipped. Run 'github-feed db doctor' to inspect them.\n",
+				warningColor.Sprint("Warning:"), skipped)
+		}
+	}
+}
+
+// fetchActivity runs one fetch cycle, from GitHub or the cache with --local,
+// saving what it finds and returning the filtered feed. It returns false when
+// the cycle was skipped because of the rate limit.
+func fetchActivity() (FeedSections, bool) {
 	startTime := time.Now()
 
 	if !config.localMode {
 		if err := checkRateLimit(); err != nil {
-			fmt.Printf("Skipping this cycle due to rate limit: %v\n", err)
-			return
+			metrics.recordFetch(startTime, true)
+			fmt.Fprintf(config.statusOut, "Skipping this cycle due to rate limit: %v\n", err)
+			return FeedSections{}, false
 		}
 		if config.debugMode {
 			fmt.Println()
 		}
 	}
 
+	// Queue database writes for the whole cycle and commit them together
+	if !config.localMode && config.db != nil {
+		config.db.BeginBatch(config.debugMode)
+	}
+
 	var seenPRs sync.Map        // Maps prKey -> label
 	activitiesMap := sync.Map{} // Maps prKey -> *PRActivity
 
@@ -674,22 +872,23 @@ func fetchAndDisplayActivity() {
 	if !config.localMode {
 		initialTotal += 3 // Add 3 for event pages
 	}
-	config.progress = &Progress{}
+	config.progress = &Progress{hidden: !isTerminal(config.statusOut)}
 	config.progress.current.Store(0)
 	config.progress.total.Store(int32(initialTotal))
 
 	if config.debugMode {
 		fmt.Println("Running optimized search queries...")
-	} else {
-		fmt.Print("Fetching data from GitHub... ")
+	} else if !config.progress.hidden {
+		fmt.Fprint(config.statusOut, "Fetching data from GitHub... ")
 		config.progress.display()
 	}
 
-	dateAgo := time.Now().Add(-config.timeRange).Format("2006-01-02")
-	dateFilter := fmt.Sprintf("updated:>=%s", dateAgo)
+	dateFilter := config.window.searchQualifier()
 
+	// Repo, label and author filters go into the query so GitHub skips those results
 	buildQuery := func(base string) string {
-		return fmt.Sprintf("%s %s", base, dateFilter)
+		query := config.itemFilter.withQualifiers(fmt.Sprintf("%s %s", base, dateFilter))
+		return config.repoFilter.withRepoQualifiers(query)
 	}
 
 	var prWg sync.WaitGroup
@@ -763,6 +962,17 @@ func fetchAndDisplayActivity() {
 		return true
 	})
 
+	config.updateMarks = applyUpdateMarks(config.db, activities, issueActivities)
+
+	// Drop bot and other noise before spending API calls on cross-references
+	if usesOwnComment(config.rules) && !config.localMode {
+		cacheLatestComments(activities, issueActivities)
+	}
+	activities, issueActivities, config.hiddenCount = applyRules(config.rules, config.showHidden, activities, issueActivities)
+	if config.debugMode && config.hiddenCount > 0 {
+		fmt.Printf("Suppression rules matched %d item(s)\n", config.hiddenCount)
+	}
+
 	if config.debugMode {
 		fmt.Println("Checking cross-references between PRs and issues...")
 	}
@@ -830,6 +1040,14 @@ func fetchAndDisplayActivity() {
 	close(resultsChan)
 	<-collectorDone
 
+	if config.db != nil {
+		written, failed := config.db.FlushBatch()
+		config.dbErrorCount.Add(int32(failed))
+		if config.debugMode && written+failed > 0 {
+			fmt.Printf("Database writes: %d saved, %d failed\n", written, failed)
+		}
+	}
+
 	standaloneIssues := []IssueActivity{}
 	for _, issue := range issueActivities {
 		issueKey := buildItemKey(issue.Owner, issue.Repo, issue.Issue.GetNumber())
@@ -851,103 +1069,17 @@ func fetchAndDisplayActivity() {
 			}
 		}
 		fmt.Println()
-	} else {
-		fmt.Print("\r" + strings.Repeat(" ", 80) + "\r")
-	}
-
-	if len(activities) == 0 && len(standaloneIssues) == 0 {
-		fmt.Println("No open activity found")
-		return
-	}
-
-	sort.Slice(activities, func(i, j int) bool {
-		return activities[i].UpdatedAt.After(activities[j].UpdatedAt)
-	})
-	sort.Slice(standaloneIssues, func(i, j int) bool {
-		return standaloneIssues[i].UpdatedAt.After(standaloneIssues[j].UpdatedAt)
-	})
-
-	var openPRs, closedPRs, mergedPRs []PRActivity
-	for _, activity := range activities {
-		if activity.PR.State != nil && *activity.PR.State == "closed" {
-			if activity.PR.Merged != nil && *activity.PR.Merged {
-				mergedPRs = append(mergedPRs, activity)
-			} else {
-				closedPRs = append(closedPRs, activity)
-			}
-		} else {
-			openPRs = append(openPRs, activity)
-		}
-	}
-
-	var openIssues, closedIssues []IssueActivity
-	for _, issue := range standaloneIssues {
-		if issue.Issue.State != nil && *issue.Issue.State == "closed" {
-			closedIssues = append(closedIssues, issue)
-		} else {
-			openIssues = append(openIssues, issue)
-		}
-	}
-
-	if len(openPRs) > 0 {
-		titleColor := color.New(color.FgHiGreen, color.Bold)
-		fmt.Println(titleColor.Sprint("OPEN PULL REQUESTS:"))
-		fmt.Println("------------------------------------------")
-		for _, activity := range openPRs {
-			displayPR(activity.Label, activity.Owner, activity.Repo, activity.PR, activity.HasUpdates)
-			if len(activity.Issues) > 0 {
-				for _, issue := range activity.Issues {
-					displayIssue(issue.Label, issue.Owner, issue.Repo, issue.Issue, true, issue.HasUpdates)
-				}
-			}
-		}
-	}
-
-	if len(closedPRs) > 0 {
-		fmt.Println()
-		titleColor := color.New(color.FgHiRed, color.Bold)
-		fmt.Println(titleColor.Sprint("CLOSED/MERGED PULL REQUESTS:"))
-		fmt.Println("------------------------------------------")
-		for _, activity := range closedPRs {
-			displayPR(activity.Label, activity.Owner, activity.Repo, activity.PR, activity.HasUpdates)
-			if len(activity.Issues) > 0 {
-				for _, issue := range activity.Issues {
-					displayIssue(issue.Label, issue.Owner, issue.Repo, issue.Issue, true, issue.HasUpdates)
-				}
-			}
-		}
-	}
-
-	if len(openIssues) > 0 {
-		fmt.Println()
-		titleColor := color.New(color.FgHiGreen, color.Bold)
-		fmt.Println(titleColor.Sprint("OPEN ISSUES:"))
-		fmt.Println("------------------------------------------")
-		for _, issue := range openIssues {
-			displayIssue(issue.Label, issue.Owner, issue.Repo, issue.Issue, false, issue.HasUpdates)
-		}
-	}
-
-	if len(closedIssues) > 0 {
-		fmt.Println()
-		titleColor := color.New(color.FgHiRed, color.Bold)
-		fmt.Println(titleColor.Sprint("CLOSED ISSUES:"))
-		fmt.Println("------------------------------------------")
-		for _, issue := range closedIssues {
-			displayIssue(issue.Label, issue.Owner, issue.Repo, issue.Issue, false, issue.HasUpdates)
-		}
+	} else if !config.progress.hidden {
+		fmt.Fprint(config.statusOut, "\r"+strings.Repeat(" ", 80)+"\r")
 	}
 
-	// Warn about database errors if any occurred
-	if dbErrors := config.dbErrorCount.Load(); dbErrors > 0 {
-		fmt.Printf("\n")
-		warningColor := color.New(color.FgYellow, color.Bold)
-		fmt.Printf("%s %d database write error(s) occurred. Offline mode may be incomplete.\n",
-			warningColor.Sprint("Warning:"), dbErrors)
-		if !config.debugMode {
-			fmt.Println("Run with --debug to see detailed error messages.")
-		}
+	activities, standaloneIssues = filterFeedItems(activities, standaloneIssues)
+	sections := buildFeedSections(activities, standaloneIssues)
+	metrics.recordFetch(startTime, false)
+	if fired := runHooks(config.db, config.hooks, hookEvents(sections)); fired > 0 && config.debugMode {
+		fmt.Printf("Ran %d hook(s)\n", fired)
 	}
+	return sections, true
 }
 
 func areCrossReferenced(pr *PRActivity, issue *IssueActivity) bool {
@@ -1065,13 +1197,34 @@ func mentionsNumber(text string, number int, owner string, repo string) bool {
 	return false
 }
 
+// prFromIssue builds a PR record from the issue form that search results (and
+// issue_comment webhooks) return for a PR. Merged and MergedAt are not part of
+// it; they stay unset until the PR itself is fetched.
+func prFromIssue(issue *github.Issue) *github.PullRequest {
+	return &github.PullRequest{
+		Number:    issue.Number,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		State:     issue.State,
+		CreatedAt: issue.CreatedAt,
+		UpdatedAt: issue.UpdatedAt,
+		ClosedAt:  issue.ClosedAt,
+		User:      issue.User,
+		Assignees: issue.Assignees,
+		Labels:    issue.Labels,
+		Milestone: issue.Milestone,
+		HTMLURL:   issue.HTMLURL,
+	}
+}
+
 func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap *sync.Map) {
 	if config.localMode {
 		if config.db == nil {
 			return
 		}
 
-		allPRs, prLabels, err := config.db.GetAllPullRequestsWithLabels(config.debugMode)
+		cutoffTime := config.window.Since
+		allPRs, prLabels, err := config.db.GetPullRequestsWithLabelsSince(cutoffTime, allowedRepoList(), config.debugMode)
 		if err != nil {
 			if config.debugMode {
 				fmt.Printf("  [%s] Error loading from database: %v\n", label, err)
@@ -1084,7 +1237,6 @@ func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap
 		}
 
 		totalFound := 0
-		cutoffTime := time.Now().Add(-config.timeRange)
 		for key, pr := range allPRs {
 			storedLabel := prLabels[key]
 
@@ -1092,7 +1244,7 @@ func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap
 				continue
 			}
 
-			if pr.GetUpdatedAt().Time.Before(cutoffTime) {
+			if !config.window.contains(pr.GetUpdatedAt().Time) {
 				continue
 			}
 
@@ -1189,9 +1341,9 @@ func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap
 		}
 
 		if retryErr != nil {
-			fmt.Printf("  [%s] Error searching after retries: %v\n", label, retryErr)
+			fmt.Fprintf(config.statusOut, "  [%s] Error searching after retries: %v\n", label, retryErr)
 			if resp != nil {
-				fmt.Printf("  [%s] Rate limit remaining: %d/%d\n", label, resp.Rate.Remaining, resp.Rate.Limit)
+				fmt.Fprintf(config.statusOut, "  [%s] Rate limit remaining: %d/%d\n", label, resp.Rate.Remaining, resp.Rate.Limit)
 			}
 			return
 		}
@@ -1209,7 +1361,7 @@ func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap
 			repoURL := *issue.RepositoryURL
 			parts := strings.Split(repoURL, "/")
 			if len(parts) < 2 {
-				fmt.Printf("  [%s] Error: Invalid repository URL format: %s\n", label, repoURL)
+				fmt.Fprintf(config.statusOut, "  [%s] Error: Invalid repository URL format: %s\n", label, repoURL)
 				continue
 			}
 			owner := parts[len(parts)-2]
@@ -1255,16 +1407,7 @@ func collectSearchResults(query, label string, seenPRs *sync.Map, activitiesMap
 					config.progress.display()
 				}
 
-				pr = &github.PullRequest{
-					Number:    issue.Number,
-					Title:     issue.Title,
-					Body:      issue.Body,
-					State:     issue.State,
-					UpdatedAt: issue.UpdatedAt,
-					User:      issue.User,
-					HTMLURL:   issue.HTMLURL,
-				}
-				// }
+				pr = prFromIssue(issue)
 
 				hasUpdates := false
 
@@ -1358,15 +1501,29 @@ type DisplayConfig struct {
 	HTMLURL    *string
 	Label      string
 	HasUpdates bool
-	IsIndented bool   // for nested display under PRs
+	IsIndented bool    // for nested display under PRs
 	State      *string // for issues nested under PRs (OPEN/CLOSED)
+	Kind       string  // "pr" or "issue", for --template
+	Linked     []IssueActivity
+	GHLabels   []*github.Label
+	Milestone  string
+	Demoted    bool
 }
 
 // displayItem is the unified display function for both PRs and issues
-func displayItem(cfg DisplayConfig) {
+func displayItem(w io.Writer, cfg DisplayConfig) {
+	if config.itemTemplate != nil {
+		displayTemplateItem(w, cfg)
+		return
+	}
+
 	dateStr := "          "
 	if cfg.UpdatedAt != nil {
-		dateStr = cfg.UpdatedAt.Format("2006/01/02")
+		if config.relativeDates {
+			dateStr = fmt.Sprintf("%-10s", relativeTime(cfg.UpdatedAt.Time))
+		} else {
+			dateStr = cfg.UpdatedAt.Format("2006/01/02")
+		}
 	}
 
 	indent := ""
@@ -1386,23 +1543,48 @@ func displayItem(cfg DisplayConfig) {
 		updateIcon = color.New(color.FgYellow, color.Bold).Sprint("● ")
 	}
 
-	fmt.Printf("%s%s%s %s %s %s/%s#%d - %s\n",
+	// Clickable refs replace the separate link line where the terminal supports them
+	ref := fmt.Sprintf("%s/%s#%d", cfg.Owner, cfg.Repo, cfg.Number)
+	showLinkLine := config.showLinks && cfg.HTMLURL != nil
+	if config.hyperlinks && cfg.HTMLURL != nil {
+		ref = hyperlink(*cfg.HTMLURL, ref)
+		showLinkLine = false
+	}
+
+	prefix := fmt.Sprintf("%s%s%s %s %s %s - ",
 		updateIcon,
 		indent,
 		dateStr,
 		labelColor.Sprint(strings.ToUpper(cfg.Label)),
 		userColor.Sprint(cfg.User),
-		cfg.Owner, cfg.Repo, cfg.Number,
-		cfg.Title,
+		ref,
 	)
 
-	if config.showLinks && cfg.HTMLURL != nil {
-		fmt.Printf("%s🔗 %s\n", linkIndent, *cfg.HTMLURL)
+	chips := ""
+	if len(cfg.GHLabels) > 0 {
+		chips = " " + labelChips(cfg.GHLabels)
+	}
+
+	title := cfg.Title
+	if config.termWidth > 0 {
+		title = truncateText(max(config.termWidth-visibleWidth(prefix)-visibleWidth(chips), 10), title)
+	}
+	if cfg.Demoted {
+		title = color.New(color.Faint).Sprint(title)
+	}
+	fmt.Fprintf(w, "%s%s%s\n", prefix, title, chips)
+
+	if showLinkLine {
+		fmt.Fprintf(w, "%s🔗 %s\n", linkIndent, *cfg.HTMLURL)
 	}
 }
 
-func displayPR(label, owner, repo string, pr *github.PullRequest, hasUpdates bool) {
-	displayItem(DisplayConfig{
+func displayPR(w io.Writer, label, owner, repo string, pr *github.PullRequest, hasUpdates, demoted bool, linked []IssueActivity) {
+	state := pr.GetState()
+	if pr.GetMerged() {
+		state = "merged"
+	}
+	displayItem(w, DisplayConfig{
 		Owner:      owner,
 		Repo:       repo,
 		Number:     pr.GetNumber(),
@@ -1413,11 +1595,17 @@ func displayPR(label, owner, repo string, pr *github.PullRequest, hasUpdates boo
 		Label:      label,
 		HasUpdates: hasUpdates,
 		IsIndented: false,
+		State:      &state,
+		Kind:       "pr",
+		Linked:     linked,
+		GHLabels:   pr.Labels,
+		Milestone:  pr.Milestone.GetTitle(),
+		Demoted:    demoted,
 	})
 }
 
-func displayIssue(label, owner, repo string, issue *github.Issue, indented bool, hasUpdates bool) {
-	displayItem(DisplayConfig{
+func displayIssue(w io.Writer, label, owner, repo string, issue *github.Issue, indented bool, hasUpdates, demoted bool) {
+	displayItem(w, DisplayConfig{
 		Owner:      owner,
 		Repo:       repo,
 		Number:     issue.GetNumber(),
@@ -1429,6 +1617,10 @@ func displayIssue(label, owner, repo string, issue *github.Issue, indented bool,
 		HasUpdates: hasUpdates,
 		IsIndented: indented,
 		State:      issue.State,
+		Kind:       "issue",
+		GHLabels:   issue.Labels,
+		Milestone:  issue.Milestone.GetTitle(),
+		Demoted:    demoted,
 	})
 }
 
@@ -1438,7 +1630,8 @@ func collectIssueSearchResults(query, label string, seenIssues *sync.Map, issueA
 			return
 		}
 
-		allIssues, issueLabels, err := config.db.GetAllIssuesWithLabels(config.debugMode)
+		cutoffTime := config.window.Since
+		allIssues, issueLabels, err := config.db.GetIssuesWithLabelsSince(cutoffTime, allowedRepoList(), config.debugMode)
 		if err != nil {
 			if config.debugMode {
 				fmt.Printf("  [%s] Error loading from database: %v\n", label, err)
@@ -1451,7 +1644,6 @@ func collectIssueSearchResults(query, label string, seenIssues *sync.Map, issueA
 		}
 
 		totalFound := 0
-		cutoffTime := time.Now().Add(-config.timeRange)
 		for key, issue := range allIssues {
 			storedLabel := issueLabels[key]
 
@@ -1459,7 +1651,7 @@ func collectIssueSearchResults(query, label string, seenIssues *sync.Map, issueA
 				continue
 			}
 
-			if issue.GetUpdatedAt().Time.Before(cutoffTime) {
+			if !config.window.contains(issue.GetUpdatedAt().Time) {
 				continue
 			}
 
@@ -1557,9 +1749,9 @@ func collectIssueSearchResults(query, label string, seenIssues *sync.Map, issueA
 		}
 
 		if retryErr != nil {
-			fmt.Printf("  [%s] Error searching after retries: %v\n", label, retryErr)
+			fmt.Fprintf(config.statusOut, "  [%s] Error searching after retries: %v\n", label, retryErr)
 			if resp != nil {
-				fmt.Printf("  [%s] Rate limit remaining: %d/%d\n", label, resp.Rate.Remaining, resp.Rate.Limit)
+				fmt.Fprintf(config.statusOut, "  [%s] Rate limit remaining: %d/%d\n", label, resp.Rate.Remaining, resp.Rate.Limit)
 			}
 			return
 		}
@@ -1577,7 +1769,7 @@ func collectIssueSearchResults(query, label string, seenIssues *sync.Map, issueA
 			repoURL := *issue.RepositoryURL
 			parts := strings.Split(repoURL, "/")
 			if len(parts) < 2 {
-				fmt.Printf("  [%s] Error: Invalid repository URL format: %s\n", label, repoURL)
+				fmt.Fprintf(config.statusOut, "  [%s] Error: Invalid repository URL format: %s\n", label, repoURL)
 				continue
 			}
 			owner := parts[len(parts)-2]
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// feedMetrics holds the operational counters exposed by /metrics and
+// "metrics --textfile". They cover the life of the process.
+type feedMetrics struct {
+	mu                sync.Mutex
+	apiCalls          map[string]int64       // by X-RateLimit-Resource
+	rateLimits        map[string]github.Rate // by resource, from checkRateLimit
+	retries           map[string]int64       // by reason: rate_limit or error
+	fetches           int64
+	skippedFetches    int64
+	lastFetchDuration time.Duration
+	lastFetch         time.Time
+}
+
+var metrics = &feedMetrics{
+	apiCalls:   make(map[string]int64),
+	rateLimits: make(map[string]github.Rate),
+	retries:    make(map[string]int64),
+}
+
+func (m *feedMetrics) countAPICall(resource string) {
+	if resource == "" {
+		resource = "unknown"
+	}
+	m.mu.Lock()
+	m.apiCalls[resource]++
+	m.mu.Unlock()
+}
+
+func (m *feedMetrics) setRateLimit(resource string, rate *github.Rate) {
+	if rate == nil {
+		return
+	}
+	m.mu.Lock()
+	m.rateLimits[resource] = *rate
+	m.mu.Unlock()
+}
+
+func (m *feedMetrics) countRetry(rateLimited bool) {
+	reason := "error"
+	if rateLimited {
+		reason = "rate_limit"
+	}
+	m.mu.Lock()
+	m.retries[reason]++
+	m.mu.Unlock()
+}
+
+// recordFetch records a fetch cycle; skipped cycles only count as skipped
+func (m *feedMetrics) recordFetch(start time.Time, skipped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if skipped {
+		m.skippedFetches++
+		return
+	}
+	m.fetches++
+	m.lastFetch = time.Now()
+	m.lastFetchDuration = m.lastFetch.Sub(start)
+}
+
+// metricsTransport counts GitHub API calls by rate limit resource
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		metrics.countAPICall(resp.Header.Get("X-RateLimit-Resource"))
+	}
+	return resp, err
+}
+
+// metricsWriter writes the Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (m metricsWriter) header(name, kind, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one value; labels are name/value pairs
+func (m metricsWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+		}
+		b.WriteString("}")
+	}
+	fmt.Fprintf(m.w, "%s %v\n", b.String(), value)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// writeFeedMetrics writes gauges describing the open items in the feed
+func writeFeedMetrics(w io.Writer, sections FeedSections, now time.Time) {
+	type openKey struct{ kind, repo, label string }
+	open := make(map[openKey]int)
+	seen := make(map[string]bool)
+	reviewRequested := 0
+	var oldest time.Time
+	for _, e := range hookEvents(sections) {
+		// Issues linked to several PRs appear once per PR
+		if seen[e.key()] || e.State != "open" {
+			continue
+		}
+		seen[e.key()] = true
+		open[openKey{e.Kind, e.Owner + "/" + e.Repo, e.Label}]++
+		if e.Kind != "pr" {
+			continue
+		}
+		if e.Label == "Review Requested" {
+			reviewRequested++
+		}
+		if !e.CreatedAt.IsZero() && (oldest.IsZero() || e.CreatedAt.Before(oldest)) {
+			oldest = e.CreatedAt
+		}
+	}
+
+	keys := make([]openKey, 0, len(open))
+	for key := range open {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		if keys[i].repo != keys[j].repo {
+			return keys[i].repo < keys[j].repo
+		}
+		return keys[i].label < keys[j].label
+	})
+
+	m := metricsWriter{w}
+	m.header("github_feed_open_items", "gauge", "Open PRs and issues in the feed by kind, repo and label.")
+	for _, key := range keys {
+		m.sample("github_feed_open_items", float64(open[key]), "kind", key.kind, "repo", key.repo, "label", key.label)
+	}
+	m.header("github_feed_review_requested", "gauge", "Open PRs waiting for your review.")
+	m.sample("github_feed_review_requested", float64(reviewRequested))
+	m.header("github_feed_oldest_open_pr_age_seconds", "gauge", "Age of the oldest open PR in the feed, 0 when there is none.")
+	age := 0.0
+	if !oldest.IsZero() {
+		age = now.Sub(oldest).Seconds()
+	}
+	m.sample("github_feed_oldest_open_pr_age_seconds", age)
+}
+
+// writeOperationalMetrics writes the API, retry, fetch and database counters
+func writeOperationalMetrics(w io.Writer) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	m := metricsWriter{w}
+
+	resources := make([]string, 0, len(metrics.apiCalls))
+	for resource := range metrics.apiCalls {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	m.header("github_feed_api_calls_total", "counter", "GitHub API calls by rate limit resource.")
+	for _, resource := range resources {
+		m.sample("github_feed_api_calls_total", float64(metrics.apiCalls[resource]), "resource", resource)
+	}
+
+	resources = resources[:0]
+	for resource := range metrics.rateLimits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	m.header("github_feed_rate_limit_remaining", "gauge", "Requests left in the current rate limit window at the last check.")
+	for _, resource := range resources {
+		m.sample("github_feed_rate_limit_remaining", float64(metrics.rateLimits[resource].Remaining), "resource", resource)
+	}
+	m.header("github_feed_rate_limit_limit", "gauge", "Requests allowed per rate limit window at the last check.")
+	for _, resource := range resources {
+		m.sample("github_feed_rate_limit_limit", float64(metrics.rateLimits[resource].Limit), "resource", resource)
+	}
+
+	m.header("github_feed_retries_total", "counter", "API calls retried, by reason.")
+	for _, reason := range []string{"error", "rate_limit"} {
+		m.sample("github_feed_retries_total", float64(metrics.retries[reason]), "reason", reason)
+	}
+
+	m.header("github_feed_fetches_total", "counter", "Completed fetch cycles.")
+	m.sample("github_feed_fetches_total", float64(metrics.fetches))
+	m.header("github_feed_skipped_fetches_total", "counter", "Fetch cycles skipped because of the rate limit.")
+	m.sample("github_feed_skipped_fetches_total", float64(metrics.skippedFetches))
+	m.header("github_feed_fetch_duration_seconds", "gauge", "Duration of the last completed fetch cycle.")
+	m.sample("github_feed_fetch_duration_seconds", metrics.lastFetchDuration.Seconds())
+	m.header("github_feed_last_fetch_timestamp_seconds", "gauge", "Unix time of the last completed fetch cycle, 0 before the first.")
+	last := 0.0
+	if !metrics.lastFetch.IsZero() {
+		last = float64(metrics.lastFetch.Unix())
+	}
+	m.sample("github_feed_last_fetch_timestamp_seconds", last)
+
+	m.header("github_feed_db_errors_total", "counter", "Failed database writes.")
+	m.sample("github_feed_db_errors_total", float64(config.dbErrorCount.Load()))
+}
+
+// writeMetricsFile replaces path atomically, as the node_exporter textfile
+// collector may read it at any time
+func writeMetricsFile(path string, sections FeedSections, now time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	writeFeedMetrics(tmp, sections, now)
+	writeOperationalMetrics(tmp)
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runMetricsCommand implements "github-feed metrics"
+func runMetricsCommand(args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	opts := registerFlags(fs)
+	textfile := fs.String("textfile", "", "Write the metrics to this .prom file for node_exporter (default stdout)")
+	fs.Parse(args)
+
+	if *textfile != "" && !strings.HasSuffix(*textfile, ".prom") {
+		return errors.New("--textfile must end in .prom for node_exporter's textfile collector")
+	}
+	// Metrics go to --textfile or stdout, never the rendered feed
+	opts.outputPath = ""
+	db := setupFeed(fs, opts)
+	if db != nil {
+		defer db.Close()
+	}
+	config.statusOut = os.Stderr
+
+	sections, ok := fetchActivity()
+	if !ok {
+		// Keep the previous textfile rather than reporting an empty feed
+		return errors.New("metrics not written because the fetch was skipped for the rate limit")
+	}
+	if *textfile == "" {
+		writeFeedMetrics(os.Stdout, sections, time.Now())
+		writeOperationalMetrics(os.Stdout)
+		return nil
+	}
+	return writeMetricsFile(*textfile, sections, time.Now())
+}
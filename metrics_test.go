@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestWriteFeedMetrics(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	created := func(d time.Duration) *github.Timestamp { return &github.Timestamp{Time: now.Add(-d)} }
+	issue := IssueActivity{Label: "Mentioned", Owner: "acme", Repo: "app", Issue: &github.Issue{
+		Number: github.Int(4), State: github.String("open"), CreatedAt: created(time.Hour)}}
+	sections := FeedSections{
+		OpenPRs: []PRActivity{
+			{Label: "Review Requested", Owner: "acme", Repo: "app", Issues: []IssueActivity{issue},
+				PR: &github.PullRequest{Number: github.Int(5), State: github.String("open"), CreatedAt: created(2 * time.Hour)}},
+			{Label: "Review Requested", Owner: "acme", Repo: "app", Issues: []IssueActivity{issue},
+				PR: &github.PullRequest{Number: github.Int(6), State: github.String("open"), CreatedAt: created(48 * time.Hour)}},
+			{Label: "Authored", Owner: "acme", Repo: `we"ird`,
+				PR: &github.PullRequest{Number: github.Int(7), State: github.String("open"), CreatedAt: created(time.Hour)}},
+		},
+		ClosedPRs: []PRActivity{{Label: "Authored", Owner: "acme", Repo: "app",
+			PR: &github.PullRequest{Number: github.Int(8), State: github.String("closed"), CreatedAt: created(90 * 24 * time.Hour)}}},
+	}
+
+	var out bytes.Buffer
+	writeFeedMetrics(&out, sections, now)
+	for _, want := range []string{
+		"# TYPE github_feed_open_items gauge\n",
+		`github_feed_open_items{kind="issue",repo="acme/app",label="Mentioned"} 1` + "\n",
+		`github_feed_open_items{kind="pr",repo="acme/app",label="Review Requested"} 2` + "\n",
+		`github_feed_open_items{kind="pr",repo="acme/we\"ird",label="Authored"} 1` + "\n",
+		"github_feed_review_requested 2\n",
+		"github_feed_oldest_open_pr_age_seconds 172800\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestOperationalMetrics(t *testing.T) {
+	saved := metrics
+	defer func() { metrics = saved }()
+	metrics = &feedMetrics{apiCalls: make(map[string]int64), rateLimits: make(map[string]github.Rate), retries: make(map[string]int64)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search" {
+			w.Header().Set("X-RateLimit-Resource", "search")
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &metricsTransport{base: http.DefaultTransport}}
+	for _, path := range []string{"/search", "/search", "/other"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	metrics.setRateLimit("core", &github.Rate{Limit: 5000, Remaining: 4321})
+	metrics.countRetry(true)
+	metrics.recordFetch(time.Now().Add(-1500*time.Millisecond), false)
+	metrics.recordFetch(time.Now(), true)
+
+	path := filepath.Join(t.TempDir(), "github_feed.prom")
+	if err := writeMetricsFile(path, FeedSections{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`github_feed_api_calls_total{resource="search"} 2` + "\n",
+		`github_feed_api_calls_total{resource="unknown"} 1` + "\n",
+		`github_feed_rate_limit_remaining{resource="core"} 4321` + "\n",
+		`github_feed_retries_total{reason="rate_limit"} 1` + "\n",
+		`github_feed_retries_total{reason="error"} 0` + "\n",
+		"github_feed_fetches_total 1\n",
+		"github_feed_skipped_fetches_total 1\n",
+		"github_feed_fetch_duration_seconds 1.5",
+		"github_feed_oldest_open_pr_age_seconds 0\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("metrics file missing %q:\n%s", want, data)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files in the textfile directory, want only the .prom file", len(entries))
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// outputFormats lists the values accepted by --format
+var outputFormats = []string{"text", "markdown", "html", "csv", "tsv"}
+
+func isValidOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// FeedSections holds the items of one fetch cycle grouped the way they are displayed
+type FeedSections struct {
+	OpenPRs      []PRActivity
+	ClosedPRs    []PRActivity // closed and merged
+	OpenIssues   []IssueActivity
+	ClosedIssues []IssueActivity
+}
+
+func (s FeedSections) isEmpty() bool {
+	return len(s.OpenPRs) == 0 && len(s.ClosedPRs) == 0 && len(s.OpenIssues) == 0 && len(s.ClosedIssues) == 0
+}
+
+// buildFeedSections sorts PRs and standalone issues by config.sortField (newest
+// update first by default) and splits them into open and closed sections
+func buildFeedSections(activities []PRActivity, standaloneIssues []IssueActivity) FeedSections {
+	sortActivities(activities, config.sortField, config.sortReverse)
+	sortIssues(standaloneIssues, config.sortField, config.sortReverse)
+
+	var sections FeedSections
+	for _, activity := range activities {
+		if activity.PR.State != nil && *activity.PR.State == "closed" {
+			sections.ClosedPRs = append(sections.ClosedPRs, activity)
+		} else {
+			sections.OpenPRs = append(sections.OpenPRs, activity)
+		}
+	}
+
+	for _, issue := range standaloneIssues {
+		if issue.Issue.State != nil && *issue.Issue.State == "closed" {
+			sections.ClosedIssues = append(sections.ClosedIssues, issue)
+		} else {
+			sections.OpenIssues = append(sections.OpenIssues, issue)
+		}
+	}
+
+	return sections
+}
+
+// prState returns "merged" for merged PRs and the GitHub state otherwise
+func prState(activity PRActivity) string {
+	if activity.PR.GetMerged() {
+		return "merged"
+	}
+	return activity.PR.GetState()
+}
+
+// renderFeed writes the sections in config.outputFormat to stdout or config.outputPath
+func renderFeed(sections FeedSections) error {
+	var w io.Writer = os.Stdout
+	if config.outputPath != "" {
+		file, err := os.Create(config.outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+
+		// Escape codes and width limits are only useful on a terminal
+		noColor, width, hyperlinks := color.NoColor, config.termWidth, config.hyperlinks
+		color.NoColor, config.termWidth, config.hyperlinks = true, 0, false
+		defer func() { color.NoColor, config.termWidth, config.hyperlinks = noColor, width, hyperlinks }()
+	}
+
+	var err error
+	switch config.outputFormat {
+	case "markdown":
+		err = renderMarkdown(w, sections)
+	case "html":
+		err = renderHTML(w, sections)
+	case "csv":
+		err = renderDelimited(w, sections, ',')
+	case "tsv":
+		err = renderDelimited(w, sections, '\t')
+	default:
+		renderText(w, sections)
+	}
+	if err != nil {
+		return err
+	}
+
+	if config.outputPath != "" {
+		fmt.Fprintf(config.statusOut, "Feed written to %s\n", config.outputPath)
+	}
+	return nil
+}
+
+// renderText prints the colored terminal feed, grouped by config.groupBy
+func renderText(w io.Writer, sections FeedSections) {
+	if sections.isEmpty() {
+		fmt.Fprintln(w, "No open activity found")
+		return
+	}
+
+	switch config.groupBy {
+	case "", "state":
+		renderStateSections(w, sections)
+	case "none":
+		printPRs(w, sections.OpenPRs)
+		printPRs(w, sections.ClosedPRs)
+		printIssues(w, sections.OpenIssues)
+		printIssues(w, sections.ClosedIssues)
+	default:
+		groupColor := color.New(color.FgHiCyan, color.Bold)
+		for i, group := range groupFeed(sections, config.groupBy) {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, groupColor.Sprint(group.summary()))
+			fmt.Fprintln(w, "==========================================")
+			renderStateSections(w, group.Sections)
+		}
+	}
+}
+
+func printPRs(w io.Writer, activities []PRActivity) {
+	for _, activity := range activities {
+		displayPR(w, activity.Label, activity.Owner, activity.Repo, activity.PR, activity.HasUpdates, activity.Demoted, activity.Issues)
+		for _, issue := range activity.Issues {
+			displayIssue(w, issue.Label, issue.Owner, issue.Repo, issue.Issue, true, issue.HasUpdates, issue.Demoted)
+		}
+	}
+}
+
+func printIssues(w io.Writer, issues []IssueActivity) {
+	for _, issue := range issues {
+		displayIssue(w, issue.Label, issue.Owner, issue.Repo, issue.Issue, false, issue.HasUpdates, issue.Demoted)
+	}
+}
+
+// renderStateSections prints the open/closed PR and issue sections that have items
+func renderStateSections(w io.Writer, sections FeedSections) {
+	printed := false
+	printHeader := func(title string, titleColor *color.Color) {
+		if printed {
+			fmt.Fprintln(w)
+		}
+		printed = true
+		fmt.Fprintln(w, titleColor.Sprint(title))
+		fmt.Fprintln(w, "------------------------------------------")
+	}
+
+	if len(sections.OpenPRs) > 0 {
+		printHeader("OPEN PULL REQUESTS:", color.New(color.FgHiGreen, color.Bold))
+		printPRs(w, sections.OpenPRs)
+	}
+
+	if len(sections.ClosedPRs) > 0 {
+		printHeader("CLOSED/MERGED PULL REQUESTS:", color.New(color.FgHiRed, color.Bold))
+		printPRs(w, sections.ClosedPRs)
+	}
+
+	if len(sections.OpenIssues) > 0 {
+		printHeader("OPEN ISSUES:", color.New(color.FgHiGreen, color.Bold))
+		printIssues(w, sections.OpenIssues)
+	}
+
+	if len(sections.ClosedIssues) > 0 {
+		printHeader("CLOSED ISSUES:", color.New(color.FgHiRed, color.Bold))
+		printIssues(w, sections.ClosedIssues)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxSearchQueryLength is GitHub's limit for a search query; longer repo
+// qualifiers are left to client-side filtering
+const maxSearchQueryLength = 256
+
+// RepoFilter decides which repositories are shown. Patterns are owner/repo
+// globs (minio/*, */docs); a pattern without a slash matches a whole owner.
+type RepoFilter struct {
+	include []string // ALLOWED_REPOS / --allowed-repos, empty allows all
+	exclude []string // EXCLUDED_REPOS / --exclude-repos
+	orgs    []string // --org
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalizeRepoPattern expands a bare owner to owner/*
+func normalizeRepoPattern(pattern string) string {
+	if !strings.Contains(pattern, "/") {
+		pattern += "/*"
+	}
+	return pattern
+}
+
+func newRepoFilter(allowed, excluded, orgs string) RepoFilter {
+	var f RepoFilter
+	for _, p := range splitList(allowed) {
+		f.include = append(f.include, normalizeRepoPattern(p))
+	}
+	for _, p := range splitList(excluded) {
+		f.exclude = append(f.exclude, normalizeRepoPattern(p))
+	}
+	for _, org := range splitList(orgs) {
+		f.orgs = append(f.orgs, org)
+	}
+	return f
+}
+
+func (f RepoFilter) isEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0 && len(f.orgs) == 0
+}
+
+// matchRepoPattern matches case-insensitively, like GitHub names
+func matchRepoPattern(pattern, ownerRepo string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(ownerRepo))
+	return err == nil && matched
+}
+
+// allows reports whether owner/repo passes the org, include and exclude filters
+func (f RepoFilter) allows(owner, repo string) bool {
+	ownerRepo := owner + "/" + repo
+
+	if len(f.orgs) > 0 {
+		found := false
+		for _, org := range f.orgs {
+			if strings.EqualFold(org, owner) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.include) > 0 {
+		found := false
+		for _, p := range f.include {
+			if matchRepoPattern(p, ownerRepo) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, p := range f.exclude {
+		if matchRepoPattern(p, ownerRepo) {
+			return false
+		}
+	}
+	return true
+}
+
+// exactRepos returns the included owner/repo names lowercased and sorted when
+// every include pattern is a literal name, or nil when the filter can't be listed
+func (f RepoFilter) exactRepos() []string {
+	if len(f.include) == 0 {
+		return nil
+	}
+	repos := make([]string, 0, len(f.include))
+	for _, p := range f.include {
+		if strings.ContainsAny(p, "*?[\\") {
+			return nil
+		}
+		repos = append(repos, strings.ToLower(p))
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// patternOwner returns the literal owner of a pattern, or "" when it contains wildcards
+func patternOwner(pattern string) string {
+	owner, _, _ := strings.Cut(pattern, "/")
+	if strings.ContainsAny(owner, "*?[\\") {
+		return ""
+	}
+	return owner
+}
+
+// searchQualifiers returns repo:, user: and org: qualifiers that let GitHub do as
+// much of the filtering as possible. Multiple positive qualifiers are ORed by
+// search, so they are only added when together they cover every included repo;
+// allows still has the final say.
+func (f RepoFilter) searchQualifiers() string {
+	var quals []string
+
+	if len(f.orgs) > 0 {
+		for _, org := range f.orgs {
+			quals = append(quals, "org:"+org)
+		}
+	} else {
+		quals = f.includeQualifiers()
+	}
+
+	for _, p := range f.exclude {
+		owner, name, _ := strings.Cut(p, "/")
+		switch {
+		case !strings.ContainsAny(p, "*?[\\"):
+			quals = append(quals, "-repo:"+p)
+		case name == "*" && patternOwner(p) != "":
+			quals = append(quals, "-user:"+owner)
+		}
+	}
+
+	return strings.Join(quals, " ")
+}
+
+// includeQualifiers returns one repo: or user: qualifier per include pattern,
+// or nil when a pattern such as */docs has no literal owner
+func (f RepoFilter) includeQualifiers() []string {
+	var quals []string
+	seen := make(map[string]bool)
+	for _, p := range f.include {
+		var q string
+		switch {
+		case !strings.ContainsAny(p, "*?[\\"):
+			q = "repo:" + p
+		case patternOwner(p) != "":
+			q = "user:" + patternOwner(p)
+		default:
+			return nil
+		}
+		if !seen[q] {
+			seen[q] = true
+			quals = append(quals, q)
+		}
+	}
+	return quals
+}
+
+// withRepoQualifiers appends the filter's qualifiers to query when they fit
+// within GitHub's query length limit
+func (f RepoFilter) withRepoQualifiers(query string) string {
+	quals := f.searchQualifiers()
+	if quals == "" || len(query)+1+len(quals) > maxSearchQueryLength {
+		return query
+	}
+	return query + " " + quals
+}
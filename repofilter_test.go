@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepoFilterAllows(t *testing.T) {
+	tests := []struct {
+		allowed, excluded, orgs string
+		repo                    string
+		want                    bool
+	}{
+		{"", "", "", "minio/minio", true},
+		{"minio/minio", "", "", "minio/minio", true},
+		{"minio/minio", "", "", "minio/mc", false},
+		{"Minio/EC", "", "", "minio/ec", true},
+		{"minio/*", "", "", "minio/mc", true},
+		{"minio/*", "", "", "miniohq/ec", false},
+		{"minio", "", "", "minio/mc", true},
+		{"*/docs", "", "", "other/docs", true},
+		{"*/docs", "", "", "other/site", false},
+		{"minio/*", "minio/mint", "", "minio/mint", false},
+		{"", "*/website", "", "minio/website", false},
+		{"", "", "minio,miniohq", "miniohq/ec", true},
+		{"", "", "minio", "other/docs", false},
+		{"*/docs", "", "minio", "other/docs", false},
+	}
+	for _, tt := range tests {
+		f := newRepoFilter(tt.allowed, tt.excluded, tt.orgs)
+		owner, repo, _ := strings.Cut(tt.repo, "/")
+		if got := f.allows(owner, repo); got != tt.want {
+			t.Errorf("filter(allowed=%q excluded=%q orgs=%q).allows(%s) = %v, want %v",
+				tt.allowed, tt.excluded, tt.orgs, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestRepoFilterSearchQualifiers(t *testing.T) {
+	tests := []struct {
+		allowed, excluded, orgs string
+		want                    string
+	}{
+		{"", "", "", ""},
+		{"minio/minio,minio/mc", "", "", "repo:minio/minio repo:minio/mc"},
+		{"minio/*,minio/ec-*,tunnels-is/tunnels", "", "", "user:minio repo:tunnels-is/tunnels"},
+		{"minio/minio,*/docs", "", "", ""},
+		{"*/docs", "minio/mint,other/*,*/site", "", "-repo:minio/mint -user:other"},
+		{"minio/minio", "", "miniohq", "org:miniohq"},
+	}
+	for _, tt := range tests {
+		f := newRepoFilter(tt.allowed, tt.excluded, tt.orgs)
+		if got := f.searchQualifiers(); got != tt.want {
+			t.Errorf("filter(allowed=%q excluded=%q orgs=%q).searchQualifiers() = %q, want %q",
+				tt.allowed, tt.excluded, tt.orgs, got, tt.want)
+		}
+	}
+}
+
+func TestRepoFilterQueryLength(t *testing.T) {
+	var repos []string
+	for i := 0; i < 30; i++ {
+		repos = append(repos, "some-organization/repository-name-"+strings.Repeat("x", i))
+	}
+	f := newRepoFilter(strings.Join(repos, ","), "", "")
+	query := "is:pr author:someone updated:>=2026-09-01T00:00:00Z"
+	if got := f.withRepoQualifiers(query); got != query {
+		t.Errorf("withRepoQualifiers() should leave over-long queries to client-side filtering, got %d chars", len(got))
+	}
+
+	f = newRepoFilter("minio/minio", "", "")
+	if got, want := f.withRepoQualifiers(query), query+" repo:minio/minio"; got != want {
+		t.Errorf("withRepoQualifiers() = %q, want %q", got, want)
+	}
+}
+
+func TestRepoFilterExactRepos(t *testing.T) {
+	if got := newRepoFilter("b/two,a/One", "", "").exactRepos(); strings.Join(got, ",") != "a/one,b/two" {
+		t.Errorf("exactRepos() = %v, want sorted lowercase names", got)
+	}
+	if got := newRepoFilter("a/one,b/*", "", "").exactRepos(); got != nil {
+		t.Errorf("exactRepos() with a pattern = %v, want nil", got)
+	}
+}
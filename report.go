@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/v57/github"
+)
+
+// reportItem is a PR or issue flattened for the markdown and HTML reports
+type reportItem struct {
+	Label      string
+	State      string
+	User       string
+	Owner      string
+	Repo       string
+	Number     int
+	Title      string
+	URL        string
+	Updated    string
+	HasUpdates bool
+	GHLabels   []reportLabel
+	Linked     []reportItem
+}
+
+// reportLabel is a GitHub label with the colors to draw its chip in
+type reportLabel struct {
+	Name       string
+	Background string
+	Text       string
+}
+
+func reportLabels(labels []*github.Label) []reportLabel {
+	var result []reportLabel
+	for _, label := range labels {
+		background, text := labelCSS(label)
+		result = append(result, reportLabel{Name: label.GetName(), Background: background, Text: text})
+	}
+	return result
+}
+
+func (r reportItem) Ref() string {
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// reportSection is one titled section of a report
+type reportSection struct {
+	Title string
+	Items []reportItem
+}
+
+func formatReportDate(ts *github.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.Format("2006/01/02")
+}
+
+func prReportItem(activity PRActivity) reportItem {
+	item := reportItem{
+		Label:      activity.Label,
+		State:      prState(activity),
+		User:       activity.PR.User.GetLogin(),
+		Owner:      activity.Owner,
+		Repo:       activity.Repo,
+		Number:     activity.PR.GetNumber(),
+		Title:      activity.PR.GetTitle(),
+		URL:        activity.PR.GetHTMLURL(),
+		Updated:    formatReportDate(activity.PR.UpdatedAt),
+		HasUpdates: activity.HasUpdates,
+		GHLabels:   reportLabels(activity.PR.Labels),
+	}
+	for _, issue := range activity.Issues {
+		item.Linked = append(item.Linked, issueReportItem(issue))
+	}
+	return item
+}
+
+func issueReportItem(issue IssueActivity) reportItem {
+	return reportItem{
+		Label:      issue.Label,
+		State:      issue.Issue.GetState(),
+		User:       issue.Issue.User.GetLogin(),
+		Owner:      issue.Owner,
+		Repo:       issue.Repo,
+		Number:     issue.Issue.GetNumber(),
+		Title:      issue.Issue.GetTitle(),
+		URL:        issue.Issue.GetHTMLURL(),
+		Updated:    formatReportDate(issue.Issue.UpdatedAt),
+		HasUpdates: issue.HasUpdates,
+		GHLabels:   reportLabels(issue.Issue.Labels),
+	}
+}
+
+// reportSections converts the feed sections into report sections, skipping empty
+// ones. Groups from config.groupBy prefix the section titles.
+func reportSections(sections FeedSections) []reportSection {
+	var result []reportSection
+
+	addPRs := func(title string, activities ...[]PRActivity) {
+		section := reportSection{Title: title}
+		for _, list := range activities {
+			for _, activity := range list {
+				section.Items = append(section.Items, prReportItem(activity))
+			}
+		}
+		if len(section.Items) > 0 {
+			result = append(result, section)
+		}
+	}
+	addIssues := func(title string, issues ...[]IssueActivity) {
+		section := reportSection{Title: title}
+		for _, list := range issues {
+			for _, issue := range list {
+				section.Items = append(section.Items, issueReportItem(issue))
+			}
+		}
+		if len(section.Items) > 0 {
+			result = append(result, section)
+		}
+	}
+	addStateSections := func(prefix string, s FeedSections) {
+		addPRs(prefix+"Open Pull Requests", s.OpenPRs)
+		addPRs(prefix+"Closed/Merged Pull Requests", s.ClosedPRs)
+		addIssues(prefix+"Open Issues", s.OpenIssues)
+		addIssues(prefix+"Closed Issues", s.ClosedIssues)
+	}
+
+	switch config.groupBy {
+	case "", "state":
+		addStateSections("", sections)
+	case "none":
+		addPRs("Pull Requests", sections.OpenPRs, sections.ClosedPRs)
+		addIssues("Issues", sections.OpenIssues, sections.ClosedIssues)
+	default:
+		for _, group := range groupFeed(sections, config.groupBy) {
+			addStateSections(group.Name+" - ", group.Sections)
+		}
+	}
+	return result
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func markdownItemLine(item reportItem) string {
+	marker := ""
+	if item.HasUpdates {
+		marker = " ●"
+	}
+	ref := item.Ref()
+	if item.URL != "" {
+		ref = fmt.Sprintf("[%s](%s)", escapeMarkdown(ref), item.URL)
+	} else {
+		ref = escapeMarkdown(ref)
+	}
+	chips := ""
+	for _, label := range item.GHLabels {
+		chips += " `" + strings.ReplaceAll(label.Name, "`", "'") + "`"
+	}
+	return fmt.Sprintf("**%s** %s %s - %s%s (@%s, %s)%s",
+		strings.ToUpper(item.Label), strings.ToUpper(item.State), ref,
+		escapeMarkdown(item.Title), chips, escapeMarkdown(item.User), item.Updated, marker)
+}
+
+// renderMarkdown writes the feed as a markdown document, with links always included
+func renderMarkdown(w io.Writer, sections FeedSections) error {
+	fmt.Fprintf(w, "# GitHub Feed - %s\n", time.Now().Format("2006-01-02"))
+
+	reports := reportSections(sections)
+	if len(reports) == 0 {
+		fmt.Fprintln(w, "\nNo open activity found")
+		return nil
+	}
+
+	for _, section := range reports {
+		fmt.Fprintf(w, "\n## %s\n\n", escapeMarkdown(section.Title))
+		for _, item := range section.Items {
+			fmt.Fprintf(w, "- %s\n", markdownItemLine(item))
+			for _, linked := range item.Linked {
+				fmt.Fprintf(w, "  - %s\n", markdownItemLine(linked))
+			}
+		}
+	}
+	return nil
+}
+
+// ansiCSSColors maps terminal colors to CSS colors for the HTML report
+var ansiCSSColors = map[color.Attribute]string{
+	color.FgRed:      "#cc3333",
+	color.FgGreen:    "#2e9e44",
+	color.FgYellow:   "#b58900",
+	color.FgBlue:     "#2b6cd6",
+	color.FgMagenta:  "#a33ea1",
+	color.FgCyan:     "#1a9aa8",
+	color.FgWhite:    "#555555",
+	color.FgHiBlack:  "#777777",
+	color.FgHiCyan:   "#27b8c9",
+	color.FgHiGreen:  "#3cbf55",
+	color.FgHiRed:    "#e04848",
+	color.FgHiYellow: "#d4a000",
+}
+
+func attributeCSS(attr color.Attribute) string {
+	if css, ok := ansiCSSColors[attr]; ok {
+		return css
+	}
+	return "#555555"
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"labelColor": func(label string) template.CSS { return template.CSS(attributeCSS(getLabelAttribute(label))) },
+	"stateColor": func(state string) template.CSS { return template.CSS(attributeCSS(getStateAttribute(state))) },
+	"upper":      strings.ToUpper,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+{{- if .Refresh}}
+<meta http-equiv="refresh" content="{{.Refresh}}">
+{{- end}}
+<title>GitHub Feed - {{.Date}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 70em; color: #24292f; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; margin-top: 1.6em; }
+ul { list-style: none; padding-left: 0; }
+ul ul { padding-left: 2em; }
+li { margin: .3em 0; }
+.ghlabel { display: inline-block; font-size: .75em; border-radius: 1em; padding: 0 .5em; margin-left: .3em; }
+.chip { display: inline-block; font-size: .75em; font-weight: 600; color: #fff; border-radius: 1em; padding: .1em .6em; margin-right: .3em; }
+.date { color: #57606a; font-family: monospace; margin-right: .4em; }
+.user { color: #57606a; }
+.updated { color: #b58900; font-weight: bold; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+</style>
+</head>
+<body>
+<h1>GitHub Feed - {{.Date}}</h1>
+{{- if not .Sections}}
+<p>No open activity found</p>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+<ul>
+{{- range .Items}}
+{{template "item" .}}
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Footer}}
+<p class="user">{{.Footer}}</p>
+{{- end}}
+</body>
+</html>
+{{define "item"}}<li>{{if .HasUpdates}}<span class="updated">● </span>{{end}}<span class="date">{{.Updated}}</span><span class="chip" style="background: {{labelColor .Label}}">{{upper .Label}}</span><span class="chip" style="background: {{stateColor .State}}">{{upper .State}}</span><a href="{{.URL}}">{{.Ref}}</a> - {{.Title}}{{range .GHLabels}}<span class="ghlabel" style="background: {{.Background}}; color: {{.Text}}">{{.Name}}</span>{{end}} <span class="user">@{{.User}}</span>
+{{- if .Linked}}
+<ul>
+{{- range .Linked}}
+{{template "item" .}}
+{{- end}}
+</ul>
+{{- end}}</li>{{end}}
+`))
+
+// renderHTML writes the feed as a standalone HTML page
+func renderHTML(w io.Writer, sections FeedSections) error {
+	return renderHTMLPage(w, sections, 0, "")
+}
+
+// renderHTMLPage writes the HTML page, reloading itself every refresh seconds
+// when refresh is set and ending with footer, as used by the serve dashboard
+func renderHTMLPage(w io.Writer, sections FeedSections, refresh int, footer string) error {
+	return htmlReportTemplate.Execute(w, struct {
+		Date     string
+		Sections []reportSection
+		Refresh  int
+		Footer   string
+	}{
+		Date:     time.Now().Format("2006-01-02"),
+		Sections: reportSections(sections),
+		Refresh:  refresh,
+		Footer:   footer,
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testReportSections covers every section, a linked issue, label chips and
+// titles that need escaping in markdown, HTML and CSV
+func testReportSections() FeedSections {
+	ts := func(day int) *github.Timestamp {
+		return &github.Timestamp{Time: time.Date(2026, 10, day, 9, 0, 0, 0, time.UTC)}
+	}
+	user := func(login string) *github.User { return &github.User{Login: github.String(login)} }
+	linked := IssueActivity{Label: "Mentioned", Owner: "acme", Repo: "app", Issue: &github.Issue{
+		Number: github.Int(4), Title: github.String("Crash when title has | pipes"), State: github.String("open"),
+		HTMLURL: github.String("https://github.com/acme/app/issues/4"), User: user("carol"), UpdatedAt: ts(3)}}
+	return FeedSections{
+		OpenPRs: []PRActivity{{
+			Label: "Review Requested", Owner: "acme", Repo: "app", HasUpdates: true,
+			PR: &github.PullRequest{Number: github.Int(5), Title: github.String(`Fix *bold* [link] <script>alert("x")</script> & _under_`),
+				State: github.String("open"), HTMLURL: github.String("https://github.com/acme/app/pull/5"), User: user("bob"),
+				UpdatedAt: ts(5), Labels: testLabels("bug")},
+			Issues: []IssueActivity{linked},
+		}},
+		ClosedPRs: []PRActivity{{
+			Label: "Authored", Owner: "acme", Repo: "lib",
+			PR: &github.PullRequest{Number: github.Int(9), Title: github.String("=cmd|' /C calc'!A0"), State: github.String("closed"),
+				Merged: github.Bool(true), HTMLURL: github.String("https://github.com/acme/lib/pull/9"), User: user("me"),
+				UpdatedAt: ts(4), MergedAt: ts(4)},
+		}},
+		OpenIssues: []IssueActivity{{
+			Label: "Assigned", Owner: "other", Repo: "docs", Issue: &github.Issue{
+				Number: github.Int(12), Title: github.String("Document `--format`, \"quotes\" and commas"), State: github.String("open"),
+				HTMLURL: github.String("https://github.com/other/docs/issues/12"), User: user("dave"), UpdatedAt: ts(2)}},
+		},
+		ClosedIssues: []IssueActivity{{
+			Label: "Authored", Owner: "other", Repo: "docs", Issue: &github.Issue{
+				Number: github.Int(11), Title: github.String("Old bug"), State: github.String("closed"),
+				HTMLURL: github.String("https://github.com/other/docs/issues/11"), User: user("me"), UpdatedAt: ts(1)}},
+		},
+	}
+}
+
+// checkGolden compares got with testdata/name, or rewrites it with -update.
+// The date in the reports' headings is replaced so they stay stable.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	got = bytes.ReplaceAll(got, []byte("GitHub Feed - "+time.Now().Format("2006-01-02")), []byte("GitHub Feed - TODAY"))
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file:\n--- got\n%s\n--- want\n%s", name, got, want)
+	}
+}
+
+func TestRenderersGolden(t *testing.T) {
+	setNoColor(t, true)
+	savedWidth, savedLinks, savedHyperlinks, savedGroup := config.termWidth, config.showLinks, config.hyperlinks, config.groupBy
+	savedRelative, savedTemplate := config.relativeDates, config.itemTemplate
+	t.Cleanup(func() {
+		config.termWidth, config.showLinks, config.hyperlinks, config.groupBy = savedWidth, savedLinks, savedHyperlinks, savedGroup
+		config.relativeDates, config.itemTemplate = savedRelative, savedTemplate
+	})
+	config.termWidth, config.showLinks, config.hyperlinks, config.groupBy = 0, true, false, "state"
+	config.relativeDates, config.itemTemplate = false, nil
+
+	sections := testReportSections()
+	renderers := []struct {
+		golden string
+		render func(*bytes.Buffer) error
+	}{
+		{"report.txt", func(b *bytes.Buffer) error { renderText(b, sections); return nil }},
+		{"report.md", func(b *bytes.Buffer) error { return renderMarkdown(b, sections) }},
+		{"report.html", func(b *bytes.Buffer) error { return renderHTML(b, sections) }},
+		{"report.csv", func(b *bytes.Buffer) error { return renderDelimited(b, sections, ',') }},
+		{"report.tsv", func(b *bytes.Buffer) error { return renderDelimited(b, sections, '\t') }},
+	}
+	for _, r := range renderers {
+		var out bytes.Buffer
+		if err := r.render(&out); err != nil {
+			t.Fatalf("%s: %v", r.golden, err)
+		}
+		checkGolden(t, r.golden, out.Bytes())
+	}
+}
+
+func TestReportEscaping(t *testing.T) {
+	sections := testReportSections()
+
+	var md bytes.Buffer
+	if err := renderMarkdown(&md, sections); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`Fix \*bold\* \[link\] \<script\>`, `\_under\_`, `Crash when title has \| pipes`} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing escaped %q:\n%s", want, md.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := renderHTML(&html, sections); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html.String(), "<script>alert") || !strings.Contains(html.String(), "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; _under_") {
+		t.Errorf("HTML title not escaped:\n%s", html.String())
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// rulesFileName is the suppression rules file in the config directory
+const rulesFileName = "rules"
+
+// Rule hides or demotes items matching all of its conditions. One rule per line:
+//
+//	hide bot label=Mentioned
+//	hide author=renovate* title="^chore\(deps\)"
+//	demote repo=minio/mint
+//	demote own-comment
+type Rule struct {
+	Action     string // "hide" or "demote"
+	Line       int
+	bot        bool
+	ownComment bool
+	kind       string
+	label      string
+	author     string
+	repo       string
+	title      *regexp.Regexp
+}
+
+// ownCommentSlack allows for an item's updated_at trailing its newest comment
+const ownCommentSlack = time.Minute
+
+// ruleItem is what a rule is matched against
+type ruleItem struct {
+	kind    string
+	label   string
+	author  *github.User
+	owner   string
+	repo    string
+	number  int
+	title   string
+	updated time.Time
+}
+
+// splitRuleFields splits a rule line on whitespace, keeping double-quoted
+// values together without interpreting backslashes
+func splitRuleFields(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inQuotes, hasField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasField = true
+		case !inQuotes && (r == ' ' || r == '\t'):
+			if hasField {
+				fields = append(fields, current.String())
+				current.Reset()
+				hasField = false
+			}
+		default:
+			current.WriteRune(r)
+			hasField = true
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+	if hasField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// parseRule parses one non-empty, non-comment line of the rules file
+func parseRule(line string, lineNumber int) (Rule, error) {
+	fields, err := splitRuleFields(line)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	rule := Rule{Action: fields[0], Line: lineNumber}
+	if rule.Action != "hide" && rule.Action != "demote" {
+		return Rule{}, fmt.Errorf("unknown action %q (use hide or demote)", rule.Action)
+	}
+	if len(fields) == 1 {
+		return Rule{}, errors.New("rule has no conditions")
+	}
+
+	for _, field := range fields[1:] {
+		switch field {
+		case "bot":
+			rule.bot = true
+			continue
+		case "own-comment":
+			rule.ownComment = true
+			continue
+		}
+
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid condition %q", field)
+		}
+		switch name {
+		case "kind":
+			if value != "pr" && value != "issue" {
+				return Rule{}, fmt.Errorf("invalid kind %q (use pr or issue)", value)
+			}
+			rule.kind = value
+		case "label":
+			rule.label = value
+		case "author":
+			if _, err := path.Match(value, ""); err != nil {
+				return Rule{}, fmt.Errorf("invalid author pattern %q: %w", value, err)
+			}
+			rule.author = value
+		case "repo":
+			if _, err := path.Match(value, ""); err != nil {
+				return Rule{}, fmt.Errorf("invalid repo pattern %q: %w", value, err)
+			}
+			rule.repo = normalizeRepoPattern(value)
+		case "title":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid title regex %q: %w", value, err)
+			}
+			rule.title = re
+		default:
+			return Rule{}, fmt.Errorf("unknown condition %q", name)
+		}
+	}
+	return rule, nil
+}
+
+// loadRules reads the rules file; a missing file means no rules
+func loadRules(filename string) ([]Rule, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseRule(line, lineNumber)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// isBot reports whether a user is a GitHub App or bot account
+func isBot(user *github.User) bool {
+	return user.GetType() == "Bot" || strings.HasSuffix(user.GetLogin(), "[bot]")
+}
+
+func (r Rule) matches(item ruleItem) bool {
+	if r.bot && !isBot(item.author) {
+		return false
+	}
+	if r.kind != "" && r.kind != item.kind {
+		return false
+	}
+	if r.label != "" && !strings.EqualFold(r.label, item.label) {
+		return false
+	}
+	if r.author != "" {
+		if matched, _ := path.Match(strings.ToLower(r.author), strings.ToLower(item.author.GetLogin())); !matched {
+			return false
+		}
+	}
+	if r.repo != "" && !matchRepoPattern(r.repo, item.owner+"/"+item.repo) {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(item.title) {
+		return false
+	}
+	// Checked last since it reads the comment cache
+	if r.ownComment && !latestActivityIsOwnComment(config.db, item) {
+		return false
+	}
+	return true
+}
+
+// usesOwnComment reports whether any rule needs the items' latest comments
+func usesOwnComment(rules []Rule) bool {
+	for _, rule := range rules {
+		if rule.ownComment {
+			return true
+		}
+	}
+	return false
+}
+
+// latestComment returns the author and time of the newest cached conversation
+// or review comment on an item
+func latestComment(db *Database, kind, owner, repo string, number int) (string, time.Time) {
+	var author string
+	var latest time.Time
+	consider := func(user *github.User, created, updated *github.Timestamp) {
+		at := created.GetTime()
+		if at == nil {
+			return
+		}
+		if u := updated.GetTime(); u != nil && u.After(*at) {
+			at = u
+		}
+		if at.After(latest) {
+			author, latest = user.GetLogin(), *at
+		}
+	}
+
+	if comments, err := db.GetIssueComments(owner, repo, number); err == nil {
+		for _, c := range comments {
+			consider(c.User, c.CreatedAt, c.UpdatedAt)
+		}
+	}
+	if kind == "pr" {
+		if comments, err := db.GetPRComments(owner, repo, number); err == nil {
+			for _, c := range comments {
+				consider(c.User, c.CreatedAt, c.UpdatedAt)
+			}
+		}
+	}
+	return author, latest
+}
+
+// latestActivityIsOwnComment reports whether the newest activity on an item is
+// a comment by the user, judged from the comments in the cache
+func latestActivityIsOwnComment(db *Database, item ruleItem) bool {
+	if db == nil || config.username == "" {
+		return false
+	}
+	author, at := latestComment(db, item.kind, item.owner, item.repo, item.number)
+	return !at.IsZero() && strings.EqualFold(author, config.username) && !at.Before(item.updated.Add(-ownCommentSlack))
+}
+
+// cacheLatestComments fetches the comments made since each item's last update
+// and caches them, so own-comment rules see the newest comment. Items whose
+// newest cached comment is already that recent are skipped.
+func cacheLatestComments(activities []PRActivity, issues []IssueActivity) {
+	if config.db == nil || config.db.readOnly {
+		return
+	}
+	var items []ruleItem
+	for _, activity := range activities {
+		items = append(items, ruleItem{kind: "pr", owner: activity.Owner, repo: activity.Repo, number: activity.PR.GetNumber(), updated: activity.PR.GetUpdatedAt().Time})
+	}
+	for _, issue := range issues {
+		items = append(items, ruleItem{kind: "issue", owner: issue.Owner, repo: issue.Repo, number: issue.Issue.GetNumber(), updated: issue.Issue.GetUpdatedAt().Time})
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan ruleItem)
+	for i := 0; i < timelineFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				since := item.updated.Add(-ownCommentSlack)
+				if _, at := latestComment(config.db, item.kind, item.owner, item.repo, item.number); !at.Before(since) {
+					continue
+				}
+				if err := fetchCommentsSince(item, since); err != nil && config.debugMode {
+					fmt.Printf("  Warning: Could not fetch comments of %s: %v\n", buildItemKey(item.owner, item.repo, item.number), err)
+				}
+			}
+		}()
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+	// The rules read the comments back before the fetch cycle's batch is flushed
+	config.db.SyncBatch()
+}
+
+// fetchCommentsSince caches an item's conversation comments, and review
+// comments for PRs, created or edited since the given time
+func fetchCommentsSince(item ruleItem, since time.Time) error {
+	var comments []*github.IssueComment
+	err := retryWithBackoff(func() error {
+		var err error
+		comments, _, err = config.client.Issues.ListComments(config.ctx, item.owner, item.repo, item.number,
+			&github.IssueListCommentsOptions{Since: &since, ListOptions: github.ListOptions{PerPage: 100}})
+		return err
+	}, fmt.Sprintf("Comments-#%d", item.number))
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := config.db.SaveComment(item.owner, item.repo, item.number, comment, "issue_comment"); err != nil {
+			config.dbErrorCount.Add(1)
+		}
+	}
+	if item.kind != "pr" {
+		return nil
+	}
+
+	var prComments []*github.PullRequestComment
+	err = retryWithBackoff(func() error {
+		var err error
+		prComments, _, err = config.client.PullRequests.ListComments(config.ctx, item.owner, item.repo, item.number,
+			&github.PullRequestListCommentsOptions{Since: since, ListOptions: github.ListOptions{PerPage: 100}})
+		return err
+	}, fmt.Sprintf("Comments-PR#%d", item.number))
+	if err != nil {
+		return err
+	}
+	for _, comment := range prComments {
+		if err := config.db.SavePRComment(item.owner, item.repo, item.number, comment, config.debugMode); err != nil {
+			config.dbErrorCount.Add(1)
+		}
+	}
+	return nil
+}
+
+// ruleAction returns the action of the first matching rule, or ""
+func ruleAction(rules []Rule, item ruleItem) string {
+	for _, rule := range rules {
+		if rule.matches(item) {
+			return rule.Action
+		}
+	}
+	return ""
+}
+
+// applyRules drops hidden items and marks demoted ones, returning how many
+// were hidden. With showHidden, hidden items are demoted instead.
+func applyRules(rules []Rule, showHidden bool, activities []PRActivity, issues []IssueActivity) ([]PRActivity, []IssueActivity, int) {
+	if len(rules) == 0 {
+		return activities, issues, 0
+	}
+
+	hidden := 0
+	keep := func(action string, demoted *bool) bool {
+		switch action {
+		case "hide":
+			hidden++
+			if !showHidden {
+				return false
+			}
+			*demoted = true
+		case "demote":
+			*demoted = true
+		}
+		return true
+	}
+
+	keptPRs := activities[:0]
+	for _, activity := range activities {
+		action := ruleAction(rules, ruleItem{
+			kind: "pr", label: activity.Label, author: activity.PR.User,
+			owner: activity.Owner, repo: activity.Repo, number: activity.PR.GetNumber(),
+			title: activity.PR.GetTitle(), updated: activity.PR.GetUpdatedAt().Time,
+		})
+		if keep(action, &activity.Demoted) {
+			keptPRs = append(keptPRs, activity)
+		}
+	}
+
+	keptIssues := issues[:0]
+	for _, issue := range issues {
+		action := ruleAction(rules, ruleItem{
+			kind: "issue", label: issue.Label, author: issue.Issue.User,
+			owner: issue.Owner, repo: issue.Repo, number: issue.Issue.GetNumber(),
+			title: issue.Issue.GetTitle(), updated: issue.Issue.GetUpdatedAt().Time,
+		})
+		if keep(action, &issue.Demoted) {
+			keptIssues = append(keptIssues, issue)
+		}
+	}
+
+	return keptPRs, keptIssues, hidden
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, rulesFileName)
+	content := `# comment
+hide bot label=Mentioned
+
+demote title="^chore\(deps\): bump" repo=minio/*
+`
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := loadRules(filename)
+	if err != nil {
+		t.Fatalf("loadRules() error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Action != "hide" || rules[1].Action != "demote" || rules[1].Line != 4 {
+		t.Fatalf("loadRules() = %+v", rules)
+	}
+	if !rules[1].title.MatchString("chore(deps): bump x from 1 to 2") {
+		t.Errorf("quoted title regex lost its backslashes: %s", rules[1].title)
+	}
+
+	if rules, err := loadRules(filepath.Join(dir, "missing")); err != nil || rules != nil {
+		t.Errorf("loadRules(missing) = %v, %v, want no rules", rules, err)
+	}
+
+	for _, bad := range []string{"ignore bot", "hide", "hide title=\"(", "hide size=3", "hide title=\"open"} {
+		if err := os.WriteFile(filename, []byte(bad+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRules(filename); err == nil || !strings.Contains(err.Error(), ":1:") {
+			t.Errorf("loadRules(%q) error = %v, want a line-numbered error", bad, err)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+	savedDB, savedUser := config.db, config.username
+	defer func() { config.db, config.username = savedDB, savedUser }()
+	config.db, config.username = db, "alice"
+
+	bot := &github.User{Login: github.String("renovate[bot]"), Type: github.String("Bot")}
+	human := &github.User{Login: github.String("alice"), Type: github.String("User")}
+	other := &github.User{Login: github.String("bob"), Type: github.String("User")}
+
+	// alice's comment is the newest activity on #3; bob replied after her on #6
+	updated := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	comment := func(number int, id int64, user *github.User, at time.Time) {
+		c := &github.IssueComment{ID: github.Int64(id), User: user, CreatedAt: &github.Timestamp{Time: at}}
+		if err := db.SaveComment("minio", "minio", number, c, "issue_comment"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comment(3, 1, human, updated)
+	comment(6, 2, human, updated.Add(-time.Hour))
+	comment(6, 3, other, updated)
+	ts := &github.Timestamp{Time: updated}
+
+	rules := []Rule{}
+	for i, line := range []string{"hide bot label=Mentioned", "demote own-comment", "hide repo=minio/mint kind=issue"} {
+		rule, err := parseRule(line, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+
+	activities := []PRActivity{
+		{Label: "Mentioned", Owner: "minio", Repo: "minio", PR: &github.PullRequest{Number: github.Int(1), User: bot}},
+		{Label: "Authored", Owner: "minio", Repo: "minio", PR: &github.PullRequest{Number: github.Int(2), User: bot}},
+		{Label: "Commented", Owner: "minio", Repo: "minio", PR: &github.PullRequest{Number: github.Int(3), User: human, UpdatedAt: ts}},
+		{Label: "Authored", Owner: "minio", Repo: "minio", PR: &github.PullRequest{Number: github.Int(6), User: human, UpdatedAt: ts}},
+	}
+	issues := []IssueActivity{
+		{Label: "Mentioned", Owner: "minio", Repo: "mint", Issue: &github.Issue{Number: github.Int(4), User: human}},
+		{Label: "Mentioned", Owner: "minio", Repo: "minio", Issue: &github.Issue{Number: github.Int(5), User: human}},
+	}
+
+	prs, kept, hidden := applyRules(rules, false, append([]PRActivity(nil), activities...), append([]IssueActivity(nil), issues...))
+	if hidden != 2 || len(prs) != 3 || len(kept) != 1 {
+		t.Fatalf("applyRules() kept %d PRs, %d issues, hidden %d; want 3, 1, 2", len(prs), len(kept), hidden)
+	}
+	if prs[0].PR.GetNumber() != 2 || prs[0].Demoted || prs[1].PR.GetNumber() != 3 || !prs[1].Demoted || prs[2].Demoted {
+		t.Errorf("applyRules() PRs = %+v, want #2, demoted #3 and #6", prs)
+	}
+
+	prs, kept, hidden = applyRules(rules, true, append([]PRActivity(nil), activities...), append([]IssueActivity(nil), issues...))
+	if hidden != 2 || len(prs) != 4 || len(kept) != 2 || !prs[0].Demoted || !kept[0].Demoted {
+		t.Errorf("applyRules(showHidden) should keep hidden items as demoted, got %+v %+v", prs, kept)
+	}
+}
+
+func TestFetchActivityOwnComment(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	updated := time.Now().UTC().Add(-time.Hour).Truncate(time.Second).Format(time.RFC3339)
+	reset := time.Now().Add(time.Hour).Unix()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/rate_limit":
+			fmt.Fprintf(w, `{"resources":{"core":{"limit":5000,"remaining":5000,"reset":%d},"search":{"limit":30,"remaining":30,"reset":%d}}}`, reset, reset)
+		case r.URL.Path == "/search/issues" && strings.Contains(r.URL.Query().Get("q"), "is:pr commenter:alice"):
+			fmt.Fprintf(w, `{"total_count":1,"items":[{"number":1,"title":"Fix","state":"open","updated_at":%q,"user":{"login":"bob"},
+				"repository_url":"https://api.github.com/repos/acme/app","pull_request":{"url":"https://api.github.com/repos/acme/app/pulls/1"}}]}`, updated)
+		case r.URL.Path == "/search/issues":
+			fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+		case r.URL.Path == "/repos/acme/app/issues/1/comments":
+			fmt.Fprintf(w, `[{"id":7,"body":"LGTM","user":{"login":"alice"},"created_at":%q}]`, updated)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer api.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(api.URL + "/")
+	rule, err := parseRule("hide own-comment", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedClient, savedCtx, savedDB, savedUser := config.client, config.ctx, config.db, config.username
+	savedRules, savedWindow, savedOut, savedFilter := config.rules, config.window, config.statusOut, config.repoFilter
+	defer func() {
+		config.client, config.ctx, config.db, config.username = savedClient, savedCtx, savedDB, savedUser
+		config.rules, config.window, config.statusOut, config.repoFilter = savedRules, savedWindow, savedOut, savedFilter
+		config.hiddenCount = 0
+	}()
+	config.client, config.ctx, config.db, config.username = client, context.Background(), db, "alice"
+	config.rules, config.window, config.statusOut = []Rule{rule}, TimeWindow{Since: time.Now().AddDate(0, 0, -7)}, io.Discard
+	config.repoFilter = RepoFilter{}
+
+	// The comment is fetched while the cycle's write batch is open
+	sections, ok := fetchActivity()
+	if !ok {
+		t.Fatal("fetchActivity() skipped the cycle")
+	}
+	if len(sections.OpenPRs) != 0 || config.hiddenCount != 1 {
+		t.Errorf("fetchActivity() kept %d PRs and hid %d, want the PR whose last activity is your comment hidden", len(sections.OpenPRs), config.hiddenCount)
+	}
+}
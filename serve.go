@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// apiItem is a PR or issue as returned by /api/items
+type apiItem struct {
+	Kind      string     `json:"kind"`
+	Owner     string     `json:"owner"`
+	Repo      string     `json:"repo"`
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	URL       string     `json:"url"`
+	User      string     `json:"user"`
+	Label     string     `json:"label"`
+	State     string     `json:"state"`
+	GHLabels  []string   `json:"gh_labels"`
+	Milestone string     `json:"milestone,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	MergedAt  *time.Time `json:"merged_at,omitempty"`
+}
+
+// apiComment is a cached comment in an item's detail
+type apiComment struct {
+	ID        int64     `json:"id"`
+	User      string    `json:"user"`
+	Body      string    `json:"body"`
+	Path      string    `json:"path,omitempty"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// apiItemDetail is returned by /api/items/{owner}/{repo}/{number}
+type apiItemDetail struct {
+	apiItem
+	Body     string       `json:"body"`
+	Comments []apiComment `json:"comments"`
+}
+
+func apiTime(ts *github.Timestamp) *time.Time {
+	if ts == nil || ts.IsZero() {
+		return nil
+	}
+	t := ts.UTC()
+	return &t
+}
+
+func apiLabels(labels []*github.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names
+}
+
+func prAPIItem(activity PRActivity) apiItem {
+	pr := activity.PR
+	return apiItem{
+		Kind:      "pr",
+		Owner:     activity.Owner,
+		Repo:      activity.Repo,
+		Number:    pr.GetNumber(),
+		Title:     pr.GetTitle(),
+		URL:       pr.GetHTMLURL(),
+		User:      pr.User.GetLogin(),
+		Label:     activity.Label,
+		State:     prState(activity),
+		GHLabels:  apiLabels(pr.Labels),
+		Milestone: pr.Milestone.GetTitle(),
+		CreatedAt: pr.GetCreatedAt().UTC(),
+		UpdatedAt: pr.GetUpdatedAt().UTC(),
+		ClosedAt:  apiTime(pr.ClosedAt),
+		MergedAt:  apiTime(pr.MergedAt),
+	}
+}
+
+func issueAPIItem(activity IssueActivity) apiItem {
+	issue := activity.Issue
+	return apiItem{
+		Kind:      "issue",
+		Owner:     activity.Owner,
+		Repo:      activity.Repo,
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		URL:       issue.GetHTMLURL(),
+		User:      issue.User.GetLogin(),
+		Label:     activity.Label,
+		State:     issue.GetState(),
+		GHLabels:  apiLabels(issue.Labels),
+		Milestone: issue.Milestone.GetTitle(),
+		CreatedAt: issue.GetCreatedAt().UTC(),
+		UpdatedAt: issue.GetUpdatedAt().UTC(),
+		ClosedAt:  apiTime(issue.ClosedAt),
+	}
+}
+
+// cachedActivity loads the PRs and issues updated inside window from the
+// database, with the configured repo, item and suppression filters applied
+func cachedActivity(db *Database, window TimeWindow) ([]PRActivity, []IssueActivity, error) {
+	prs, prLabels, err := db.GetPullRequestsWithLabelsSince(window.Since, allowedRepoList(), config.debugMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	var activities []PRActivity
+	for key, pr := range prs {
+		owner, repo, _, err := parseItemRef(key)
+		updated := pr.GetUpdatedAt().Time
+		if err != nil || !window.contains(updated) || !isRepoAllowed(owner, repo) {
+			continue
+		}
+		activities = append(activities, PRActivity{Label: prLabels[key], Owner: owner, Repo: repo, PR: pr, UpdatedAt: updated})
+	}
+
+	issues, issueLabels, err := db.GetIssuesWithLabelsSince(window.Since, allowedRepoList(), config.debugMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	var issueActivities []IssueActivity
+	for key, issue := range issues {
+		owner, repo, _, err := parseItemRef(key)
+		updated := issue.GetUpdatedAt().Time
+		if err != nil || !window.contains(updated) || !isRepoAllowed(owner, repo) {
+			continue
+		}
+		issueActivities = append(issueActivities, IssueActivity{Label: issueLabels[key], Owner: owner, Repo: repo, Issue: issue, UpdatedAt: updated})
+	}
+
+	activities, issueActivities, _ = applyRules(config.rules, config.showHidden, activities, issueActivities)
+	activities, issueActivities = filterFeedItems(activities, issueActivities)
+	return activities, issueActivities, nil
+}
+
+// itemQuery holds the /api/items query parameters
+type itemQuery struct {
+	since  time.Time
+	until  time.Time // exclusive, zero for no limit
+	kinds  []string
+	labels []string
+	states []string
+	repos  []string
+	limit  int
+}
+
+// parseSince accepts a date (2026-09-01) or a --time range (1w, yesterday, ...)
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := parseDate(value); err == nil {
+		return t, nil
+	}
+	window, err := parseTimeWindow(value, "", "", now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return window.Since, nil
+}
+
+// parseItemQuery reads the query parameters; since defaults to the start of
+// window, and the end of window always applies
+func parseItemQuery(values map[string][]string, window TimeWindow, now time.Time) (itemQuery, error) {
+	get := func(key string) string {
+		if v := values[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	q := itemQuery{
+		since:  window.Since,
+		until:  window.Until,
+		kinds:  splitList(get("kind")),
+		labels: splitList(get("label")),
+		states: splitList(get("state")),
+	}
+	for _, repo := range splitList(get("repo")) {
+		q.repos = append(q.repos, normalizeRepoPattern(repo))
+	}
+	if since := get("since"); since != "" {
+		t, err := parseSince(since, now)
+		if err != nil {
+			return q, fmt.Errorf("invalid since: %w", err)
+		}
+		q.since = t
+	}
+	if limit := get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid limit %q", limit)
+		}
+		q.limit = n
+	}
+	return q, nil
+}
+
+func (q itemQuery) matches(item apiItem) bool {
+	if !(TimeWindow{Since: q.since, Until: q.until}).contains(item.UpdatedAt) {
+		return false
+	}
+	if len(q.kinds) > 0 && !containsFold(q.kinds, item.Kind) {
+		return false
+	}
+	if len(q.labels) > 0 && !containsFold(q.labels, item.Label) {
+		return false
+	}
+	if len(q.states) > 0 && !containsFold(q.states, item.State) {
+		return false
+	}
+	if len(q.repos) > 0 {
+		matched := false
+		for _, pattern := range q.repos {
+			if matchRepoPattern(pattern, item.Owner+"/"+item.Repo) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// feedServer serves the cached feed over HTTP while an optional fetch loop
+// keeps the cache current
+type feedServer struct {
+	db       *Database // open for the server's lifetime unless readOnly
+	dbPath   string
+	readOnly bool
+	interval time.Duration
+
+	// The --time, --since and --until values, resolved again for every fetch
+	// and request so relative ranges follow the clock
+	timeRange string
+	since     string
+	until     string
+
+	mu        sync.Mutex
+	lastFetch time.Time
+	lastError string
+	log       io.Writer // fetch status lines
+}
+
+// window returns the time window as of now
+func (s *feedServer) window() TimeWindow {
+	window, err := parseTimeWindow(s.timeRange, s.since, s.until, time.Now())
+	if err != nil {
+		// The flags were validated at startup, so this doesn't happen in practice
+		return config.window
+	}
+	return window
+}
+
+// withDB runs fn with the database. In read-only mode the cache is opened per
+// request, so another instance can keep writing to it.
+func (s *feedServer) withDB(fn func(*Database) error) error {
+	if !s.readOnly {
+		return fn(s.db)
+	}
+	db, err := OpenDatabaseReadOnly(s.dbPath, config.debugMode)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(db)
+}
+
+// fetchLoop fetches from GitHub every interval until ctx is done
+func (s *feedServer) fetchLoop(ctx context.Context) {
+	for {
+		start := time.Now()
+		config.window = s.window()
+		sections, ok := fetchActivity()
+		s.mu.Lock()
+		if ok {
+			s.lastFetch = time.Now()
+			s.lastError = ""
+			fmt.Fprintf(s.log, "[%s] Fetched %d PRs and %d issues in %v\n", start.Format("15:04:05"),
+				len(sections.OpenPRs)+len(sections.ClosedPRs), len(sections.OpenIssues)+len(sections.ClosedIssues),
+				time.Since(start).Round(time.Millisecond))
+		} else {
+			s.lastError = "fetch skipped because of the rate limit"
+			fmt.Fprintf(s.log, "[%s] Fetch skipped because of the rate limit\n", start.Format("15:04:05"))
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// items returns the cached items matching q, newest first
+func (s *feedServer) items(q itemQuery) ([]apiItem, error) {
+	var items []apiItem
+	err := s.withDB(func(db *Database) error {
+		activities, issues, err := cachedActivity(db, TimeWindow{Since: q.since, Until: q.until})
+		if err != nil {
+			return err
+		}
+		for _, activity := range activities {
+			if item := prAPIItem(activity); q.matches(item) {
+				items = append(items, item)
+			}
+		}
+		for _, issue := range issues {
+			if item := issueAPIItem(issue); q.matches(item) {
+				items = append(items, item)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].UpdatedAt.Equal(items[j].UpdatedAt) {
+			return items[i].UpdatedAt.After(items[j].UpdatedAt)
+		}
+		return items[i].URL < items[j].URL
+	})
+	if q.limit > 0 && len(items) > q.limit {
+		items = items[:q.limit]
+	}
+	return items, nil
+}
+
+func (s *feedServer) handleItems(w http.ResponseWriter, r *http.Request) {
+	q, err := parseItemQuery(r.URL.Query(), s.window(), time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := s.items(q)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if items == nil {
+		items = []apiItem{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(items), "items": items})
+}
+
+func (s *feedServer) handleItem(w http.ResponseWriter, r *http.Request) {
+	owner, repo := r.PathValue("owner"), r.PathValue("repo")
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil || number < 1 {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid item number %q", r.PathValue("number")))
+		return
+	}
+	if !isRepoAllowed(owner, repo) {
+		writeJSONError(w, http.StatusNotFound, errors.New("item not found"))
+		return
+	}
+
+	var detail *apiItemDetail
+	err = s.withDB(func(db *Database) error {
+		if pr, label, err := db.GetPullRequestWithLabel(owner, repo, number); err == nil {
+			detail = &apiItemDetail{apiItem: prAPIItem(PRActivity{Label: label, Owner: owner, Repo: repo, PR: pr}), Body: pr.GetBody()}
+			comments, err := db.GetPRComments(owner, repo, number)
+			if err != nil {
+				return err
+			}
+			for _, c := range comments {
+				detail.Comments = append(detail.Comments, apiComment{
+					ID: c.GetID(), User: c.User.GetLogin(), Body: c.GetBody(), Path: c.GetPath(),
+					URL: c.GetHTMLURL(), CreatedAt: c.GetCreatedAt().UTC(),
+				})
+			}
+		} else if issue, label, err := db.GetIssueWithLabel(owner, repo, number); err == nil {
+			detail = &apiItemDetail{apiItem: issueAPIItem(IssueActivity{Label: label, Owner: owner, Repo: repo, Issue: issue}), Body: issue.GetBody()}
+		} else {
+			return nil
+		}
+
+		// Conversation comments belong to PRs and issues alike
+		comments, err := db.GetIssueComments(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			detail.Comments = append(detail.Comments, apiComment{
+				ID: c.GetID(), User: c.User.GetLogin(), Body: c.GetBody(),
+				URL: c.GetHTMLURL(), CreatedAt: c.GetCreatedAt().UTC(),
+			})
+		}
+		sort.SliceStable(detail.Comments, func(i, j int) bool {
+			return detail.Comments[i].CreatedAt.Before(detail.Comments[j].CreatedAt)
+		})
+		return nil
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if detail == nil {
+		writeJSONError(w, http.StatusNotFound, errors.New("item not found"))
+		return
+	}
+	if detail.Comments == nil {
+		detail.Comments = []apiComment{}
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *feedServer) handleAtom(w http.ResponseWriter, r *http.Request) {
+	var entries []feedEntry
+	err := s.withDB(func(db *Database) error {
+		var err error
+		entries, err = buildFeedEntries(db, s.window(), 200)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	writeAtom(w, "GitHub Feed", entries)
+}
+
+// status describes the last fetch for the dashboard footer
+func (s *feedServer) status() string {
+	if s.readOnly {
+		return "Read-only: showing the local cache"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := "Waiting for the first fetch"
+	if !s.lastFetch.IsZero() {
+		status = "Last fetched " + s.lastFetch.Format("15:04:05") + ", refreshing every " + s.interval.String()
+	}
+	if s.lastError != "" {
+		status += " (" + s.lastError + ")"
+	}
+	return status
+}
+
+func (s *feedServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	var sections FeedSections
+	err := s.withDB(func(db *Database) error {
+		activities, issues, err := cachedActivity(db, s.window())
+		if err != nil {
+			return err
+		}
+		sections = buildFeedSections(activities, issues)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	renderHTMLPage(w, sections, 60, s.status())
+}
+
+// handleMetrics serves Prometheus metrics for the cached feed and the fetch loop
+func (s *feedServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var sections FeedSections
+	err := s.withDB(func(db *Database) error {
+		activities, issues, err := cachedActivity(db, s.window())
+		if err != nil {
+			return err
+		}
+		sections = buildFeedSections(activities, issues)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeFeedMetrics(w, sections, time.Now())
+	writeOperationalMetrics(w)
+}
+
+func (s *feedServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/items", s.handleItems)
+	mux.HandleFunc("GET /api/items/{owner}/{repo}/{number}", s.handleItem)
+	mux.HandleFunc("GET /feed.atom", s.handleAtom)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /{$}", s.handleDashboard)
+	return mux
+}
+
+// runServeCommand implements "github-feed serve"
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	opts := registerFlags(fs)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	interval := fs.Duration("interval", 5*time.Minute, "How often to fetch from GitHub")
+	readOnly := fs.Bool("read-only", false, "Serve the local cache without fetching (can run next to another instance)")
+	fs.Parse(args)
+
+	if *readOnly {
+		opts.localMode = true
+	}
+	if *interval < time.Minute {
+		return fmt.Errorf("--interval must be at least 1m to stay within GitHub's rate limits")
+	}
+
+	db := setupFeed(fs, opts)
+	// Not a terminal, so fetches report problems without drawing progress bars
+	config.statusOut = struct{ io.Writer }{os.Stderr}
+	s := &feedServer{readOnly: *readOnly, interval: *interval, log: config.statusOut,
+		timeRange: opts.timeRangeStr, since: opts.sinceFlag, until: opts.untilFlag}
+	if *readOnly {
+		// Requests open the cache themselves
+		if db != nil {
+			db.Close()
+		}
+		configDir, err := getConfigDir()
+		if err != nil {
+			return err
+		}
+		s.dbPath = filepath.Join(configDir, "github.db")
+	} else {
+		if db == nil {
+			return errors.New("serve needs the database; use --read-only to serve another instance's cache")
+		}
+		db.holdLock()
+		defer db.Close()
+		s.db = db
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if !*readOnly {
+		go s.fetchLoop(ctx)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving the feed on http://%s (API at /api/items, Atom at /feed.atom, metrics at /metrics)\n", displayAddr(*addr))
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// displayAddr turns ":8080" into "localhost:8080" for the startup message
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestServeAPI(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ts := func(d time.Duration) *github.Timestamp { return &github.Timestamp{Time: now.Add(-d)} }
+	prs := []*github.PullRequest{
+		{Number: github.Int(1), Title: github.String("Open PR"), State: github.String("open"), UpdatedAt: ts(time.Hour),
+			Labels: []*github.Label{{Name: github.String("bug")}}},
+		{Number: github.Int(2), Title: github.String("Merged PR"), State: github.String("closed"), UpdatedAt: ts(2 * time.Hour),
+			Merged: github.Bool(true), ClosedAt: ts(2 * time.Hour), MergedAt: ts(2 * time.Hour)},
+		{Number: github.Int(3), Title: github.String("Old PR"), State: github.String("open"), UpdatedAt: ts(30 * 24 * time.Hour)},
+	}
+	for _, pr := range prs {
+		if err := db.SavePullRequestWithLabel("owner", "app", pr, "Authored", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issue := &github.Issue{Number: github.Int(4), Title: github.String("Issue"), State: github.String("open"), UpdatedAt: ts(3 * time.Hour)}
+	if err := db.SaveIssueWithLabel("other", "docs", issue, "Mentioned", false); err != nil {
+		t.Fatal(err)
+	}
+	comment := &github.PullRequestComment{ID: github.Int64(7), Body: github.String("Looks good"), User: &github.User{Login: github.String("bob")},
+		CreatedAt: ts(30 * time.Minute)}
+	if err := db.SavePRComment("owner", "app", 1, comment, false); err != nil {
+		t.Fatal(err)
+	}
+	// Conversation comments, as webhooks and own-comment rules cache them
+	for _, c := range []struct {
+		owner, repo string
+		number      int
+		id          int64
+		user        string
+		age         time.Duration
+	}{
+		{"owner", "app", 1, 8, "carol", 45 * time.Minute},
+		{"other", "docs", 4, 9, "dave", 2 * time.Hour},
+	} {
+		ic := &github.IssueComment{ID: github.Int64(c.id), Body: github.String("Thanks"), User: &github.User{Login: github.String(c.user)},
+			CreatedAt: ts(c.age)}
+		if err := db.SaveComment(c.owner, c.repo, c.number, ic, "issue_comment"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &feedServer{db: db, timeRange: "1w", log: io.Discard}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	get := func(path string, wantStatus int, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("GET %s status = %d, want %d", path, resp.StatusCode, wantStatus)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+		}
+	}
+
+	numbers := func(path string) string {
+		t.Helper()
+		var result struct{ Items []apiItem }
+		get(path, http.StatusOK, &result)
+		var got []string
+		for _, item := range result.Items {
+			got = append(got, item.Repo+"#"+item.State+"#"+github.Stringify(item.Number))
+		}
+		return strings.Join(got, ",")
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "app#open#1,app#merged#2,docs#open#4"},
+		{"?state=merged", "app#merged#2"},
+		{"?label=mentioned", "docs#open#4"},
+		{"?repo=owner/*&kind=pr", "app#open#1,app#merged#2"},
+		{"?since=60d", "app#open#1,app#merged#2,docs#open#4,app#open#3"},
+		{"?limit=1", "app#open#1"},
+	}
+	for _, tt := range tests {
+		if got := numbers("/api/items" + tt.query); got != tt.want {
+			t.Errorf("/api/items%s = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+	get("/api/items?since=nonsense", http.StatusBadRequest, nil)
+
+	var detail apiItemDetail
+	get("/api/items/owner/app/1", http.StatusOK, &detail)
+	if detail.Title != "Open PR" || len(detail.GHLabels) != 1 || len(detail.Comments) != 2 ||
+		detail.Comments[0].User != "carol" || detail.Comments[1].User != "bob" {
+		t.Errorf("/api/items/owner/app/1 = %+v, want the PR with its bug label, carol's comment then bob's review comment", detail)
+	}
+	detail = apiItemDetail{}
+	get("/api/items/other/docs/4", http.StatusOK, &detail)
+	if detail.Kind != "issue" || detail.Label != "Mentioned" || len(detail.Comments) != 1 || detail.Comments[0].User != "dave" {
+		t.Errorf("/api/items/other/docs/4 = %+v, want the mentioned issue with dave's comment", detail)
+	}
+	get("/api/items/owner/app/99", http.StatusNotFound, nil)
+	get("/feed.atom", http.StatusOK, nil)
+	get("/metrics", http.StatusOK, nil)
+	get("/", http.StatusOK, nil)
+	get("/missing", http.StatusNotFound, nil)
+
+	// --until caps the served items, even when a request asks for an earlier since
+	s.timeRange, s.until = "", now.Add(-90*time.Minute).Format(time.RFC3339)
+	s.since = now.AddDate(0, 0, -7).Format(time.RFC3339)
+	for path, want := range map[string]string{
+		"/api/items":           "app#merged#2,docs#open#4",
+		"/api/items?since=60d": "app#merged#2,docs#open#4,app#open#3",
+	} {
+		if got := numbers(path); got != want {
+			t.Errorf("%s with --until = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the structured config file in the config directory
+const configFileName = "config.yaml"
+
+// Sources an effective setting can come from, highest precedence first
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceDotEnv  = ".env"
+	sourceConfig  = "config"
+	sourceDefault = "default"
+)
+
+// configValue is an option value from config.yaml; lists are joined with
+// commas, the way the comma-separated flags take them
+type configValue string
+
+func (v *configValue) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			*v = ""
+			return nil
+		}
+		*v = configValue(node.Value)
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list items must be plain values", item.Line)
+			}
+			items = append(items, item.Value)
+		}
+		*v = configValue(strings.Join(items, ","))
+	default:
+		return fmt.Errorf("line %d: expected a value or a list", node.Line)
+	}
+	return nil
+}
+
+// FileConfig is the parsed config.yaml. Top-level keys besides the sections
+// below are options named like their flag (time, format, allowed-repos, ...),
+// plus username, sprint-days and sprint-start.
+type FileConfig struct {
+	Options       map[string]configValue `yaml:",inline"`
+	LabelPriority struct {
+		PR    map[string]int `yaml:"pr"`
+		Issue map[string]int `yaml:"issue"`
+	} `yaml:"label-priority"`
+	LabelColors map[string]string                 `yaml:"label-colors"`
+	Views       map[string]map[string]configValue `yaml:"views"`
+	Hooks       []hookConfig                      `yaml:"hooks"`
+}
+
+// loadConfigFile reads config.yaml; a missing file means an empty config
+func loadConfigFile(filename string) (FileConfig, error) {
+	var cfg FileConfig
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	cfg.Options = normalizeOptionKeys(cfg.Options)
+	for name, view := range cfg.Views {
+		cfg.Views[name] = normalizeOptionKeys(view)
+	}
+	return cfg, nil
+}
+
+// optionAliases are shorter or friendlier names accepted for options in config.yaml
+var optionAliases = map[string]string{
+	"repos":  "allowed-repos",
+	"labels": "label",
+}
+
+// normalizeOptionKey maps group_by to group-by and aliases such as repos to their option
+func normalizeOptionKey(key string) string {
+	key = strings.ReplaceAll(key, "_", "-")
+	if option, ok := optionAliases[key]; ok {
+		return option
+	}
+	return key
+}
+
+func normalizeOptionKeys(options map[string]configValue) map[string]configValue {
+	if options == nil {
+		return nil
+	}
+	normalized := make(map[string]configValue, len(options))
+	for key, value := range options {
+		normalized[normalizeOptionKey(key)] = value
+	}
+	return normalized
+}
+
+// loadEnvFile reads KEY=VALUE lines from the legacy .env file. Values may be
+// single- or double-quoted, and lines may start with "export".
+func loadEnvFile(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = unquoteEnvValue(strings.TrimSpace(value))
+	}
+	return values, scanner.Err()
+}
+
+// unquoteEnvValue strips matching quotes, or a trailing " # comment" from an unquoted value
+func unquoteEnvValue(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// setting is an option that isn't a flag, or a flag with its own environment variables
+type setting struct {
+	key    string
+	env    []string
+	def    string
+	secret bool
+}
+
+// extraSettings lists the options without a flag and the flags read from
+// their historical environment variables. Every option can also be set with
+// GITHUB_FEED_<KEY>, e.g. GITHUB_FEED_GROUP_BY.
+var extraSettings = []setting{
+	{key: "username", env: []string{"GITHUB_USERNAME", "GITHUB_USER"}},
+	{key: "token", env: []string{"GITHUB_ACTIVITY_TOKEN", "GITHUB_TOKEN"}, secret: true},
+	{key: "token-source", env: []string{"GITHUB_TOKEN_SOURCE"}, def: "auto"},
+	{key: "token-command", env: []string{"GITHUB_TOKEN_COMMAND"}},
+	{key: "app-id", env: []string{"GITHUB_APP_ID"}},
+	{key: "app-installation-id", env: []string{"GITHUB_APP_INSTALLATION_ID"}},
+	{key: "app-private-key-file", env: []string{"GITHUB_APP_PRIVATE_KEY_FILE"}},
+	{key: "app-private-key", env: []string{"GITHUB_APP_PRIVATE_KEY"}, secret: true},
+	{key: "allowed-repos", env: []string{"ALLOWED_REPOS"}},
+	{key: "exclude-repos", env: []string{"EXCLUDED_REPOS"}},
+	{key: "sprint-days", env: []string{"SPRINT_DAYS"}, def: strconv.Itoa(defaultSprintDays)},
+	{key: "sprint-start", env: []string{"SPRINT_START"}},
+	{key: "webhook-secret", env: []string{"GITHUB_WEBHOOK_SECRET"}, secret: true},
+	{key: "smtp", env: []string{"SMTP_SERVER"}},
+	{key: "smtp-user", env: []string{"SMTP_USER"}},
+	{key: "smtp-password", env: []string{"SMTP_PASSWORD"}, secret: true},
+	{key: "digest-to"},
+	{key: "digest-from"},
+}
+
+// fileOnlyExcluded are options that make no sense to set permanently
+var fileOnlyExcluded = map[string]string{
+	"clean":           "--clean deletes the cache and can only be given on the command line",
+	"token":           "tokens are not read from config.yaml; use GITHUB_TOKEN or the .env file",
+	"app-private-key": "private keys are not read from config.yaml; use app-private-key-file or GITHUB_APP_PRIVATE_KEY",
+	"webhook-secret":  "secrets are not read from config.yaml; use GITHUB_WEBHOOK_SECRET or the .env file",
+	"smtp-password":   "passwords are not read from config.yaml; use SMTP_PASSWORD or the .env file",
+}
+
+func findExtraSetting(key string) (setting, bool) {
+	for _, s := range extraSettings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// settingEnvNames returns the environment variables checked for key, in order
+func settingEnvNames(key string) []string {
+	var names []string
+	if s, ok := findExtraSetting(key); ok {
+		names = append(names, s.env...)
+	}
+	return append(names, "GITHUB_FEED_"+strings.ToUpper(strings.ReplaceAll(key, "-", "_")))
+}
+
+// resolvedSetting is the effective value of one option and where it came from
+type resolvedSetting struct {
+	Key    string
+	Value  string
+	Source string
+	Origin string // the environment variable or flag that set it
+	secret bool
+}
+
+// Settings holds the effective options after applying precedence
+// flag > environment > .env > config.yaml > default
+type Settings struct {
+	Path   string
+	File   FileConfig
+	order  []string
+	values map[string]resolvedSetting
+}
+
+// settings is the effective configuration of this run, nil until loadSettings
+var settings *Settings
+
+// settingValue returns the effective value of key. Before settings are loaded
+// it falls back to the environment so helpers work in tests and subcommands.
+func settingValue(key string) string {
+	if settings != nil {
+		return settings.values[key].Value
+	}
+	for _, name := range settingEnvNames(key) {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	if s, ok := findExtraSetting(key); ok {
+		return s.def
+	}
+	return ""
+}
+
+// isKnownOption reports whether key may appear in config.yaml or a view
+func isKnownOption(fs *flag.FlagSet, key string) error {
+	if reason, ok := fileOnlyExcluded[key]; ok {
+		return errors.New(reason)
+	}
+	if fs.Lookup(key) != nil {
+		return nil
+	}
+	if _, ok := findExtraSetting(key); ok {
+		return nil
+	}
+	return fmt.Errorf("unknown option %q", key)
+}
+
+// loadSettings resolves every option of fs and extraSettings, setting each
+// flag that wasn't given on the command line to its effective value
+func loadSettings(fs *flag.FlagSet, configDir string) (*Settings, error) {
+	dotEnv, err := loadEnvFile(filepath.Join(configDir, ".env"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	s := &Settings{
+		Path:   filepath.Join(configDir, configFileName),
+		values: make(map[string]resolvedSetting),
+	}
+	s.File, err = loadConfigFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range s.File.Options {
+		if err := isKnownOption(fs, key); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", s.Path, key, err)
+		}
+	}
+	for name, view := range s.File.Views {
+		for key := range view {
+			if err := isKnownOption(fs, key); err != nil {
+				return nil, fmt.Errorf("%s: view %s: %s: %w", s.Path, name, key, err)
+			}
+		}
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	resolve := func(key, def string, f *flag.Flag, secret bool) resolvedSetting {
+		r := resolvedSetting{Key: key, secret: secret}
+		if given[key] {
+			r.Value, r.Source, r.Origin = f.Value.String(), sourceFlag, "--"+key
+			return r
+		}
+		for _, name := range settingEnvNames(key) {
+			if value := os.Getenv(name); value != "" {
+				r.Value, r.Source, r.Origin = value, sourceEnv, name
+				return r
+			}
+		}
+		for _, name := range settingEnvNames(key) {
+			if value := dotEnv[name]; value != "" {
+				r.Value, r.Source, r.Origin = value, sourceDotEnv, name
+				return r
+			}
+		}
+		if value := s.File.Options[key]; value != "" {
+			r.Value, r.Source = string(value), sourceConfig
+			return r
+		}
+		r.Value, r.Source = def, sourceDefault
+		return r
+	}
+
+	var setErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, excluded := fileOnlyExcluded[f.Name]; excluded || setErr != nil {
+			return
+		}
+		r := resolve(f.Name, f.DefValue, f, false)
+		if r.Source != sourceFlag && r.Source != sourceDefault {
+			if err := fs.Set(f.Name, r.Value); err != nil {
+				setErr = fmt.Errorf("invalid %s from %s: %w", f.Name, r.describeSource(s.Path), err)
+				return
+			}
+		}
+		s.values[f.Name] = r
+		s.order = append(s.order, f.Name)
+	})
+	if setErr != nil {
+		return nil, setErr
+	}
+
+	for _, extra := range extraSettings {
+		if _, ok := s.values[extra.key]; ok {
+			continue
+		}
+		s.values[extra.key] = resolve(extra.key, extra.def, nil, extra.secret)
+		s.order = append(s.order, extra.key)
+	}
+	return s, nil
+}
+
+// describeSource says where a setting came from, for errors and config show
+func (r resolvedSetting) describeSource(configPath string) string {
+	switch r.Source {
+	case sourceEnv:
+		return "environment variable " + r.Origin
+	case sourceDotEnv:
+		return ".env " + r.Origin
+	case sourceConfig:
+		return configPath
+	}
+	return r.Source
+}
+
+// colorName returns the config name of a label color
+func colorName(attr color.Attribute) string {
+	names := make([]string, 0, len(namedColors))
+	for name := range namedColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if namedColors[name] == attr {
+			return name
+		}
+	}
+	return strconv.Itoa(int(attr))
+}
+
+// applyLabelSettings overrides the built-in label priorities and colors with
+// those from config.yaml
+func (s *Settings) applyLabelSettings() error {
+	for label, priority := range s.File.LabelPriority.PR {
+		if _, ok := prLabelPriorities[label]; !ok {
+			return fmt.Errorf("%s: label-priority.pr: unknown label %q", s.Path, label)
+		}
+		prLabelPriorities[label] = priority
+	}
+	for label, priority := range s.File.LabelPriority.Issue {
+		if _, ok := issueLabelPriorities[label]; !ok {
+			return fmt.Errorf("%s: label-priority.issue: unknown label %q", s.Path, label)
+		}
+		issueLabelPriorities[label] = priority
+	}
+	for label, name := range s.File.LabelColors {
+		if _, ok := labelColorAttributes[label]; !ok {
+			return fmt.Errorf("%s: label-colors: unknown label %q", s.Path, label)
+		}
+		attr, ok := namedColors[name]
+		if !ok {
+			return fmt.Errorf("%s: label-colors: unknown color %q for %s", s.Path, name, label)
+		}
+		labelColorAttributes[label] = attr
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a label map ordered by value, then name
+func sortedKeys[V int | color.Attribute](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] < m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// writeSettings prints the effective configuration with the source of each key
+func writeSettings(w io.Writer, s *Settings) {
+	fileState := ""
+	if _, err := os.Stat(s.Path); os.IsNotExist(err) {
+		fileState = " (not found)"
+	}
+	fmt.Fprintf(w, "# Config file: %s%s\n", s.Path, fileState)
+	fmt.Fprintln(w, "# Precedence: flag > env > .env > config > default")
+	fmt.Fprintln(w)
+
+	width := 0
+	for _, key := range s.order {
+		width = max(width, len(key))
+	}
+	for _, key := range s.order {
+		r := s.values[key]
+		value := r.Value
+		if r.secret && value != "" {
+			value = "********"
+		}
+		if value == "" {
+			value = `""`
+		}
+		source := r.Source
+		if r.Origin != "" && r.Source != sourceFlag {
+			source += " " + r.Origin
+		}
+		fmt.Fprintf(w, "%-*s  %-30s  %s\n", width, key, value, source)
+	}
+
+	labelSource := func(m map[string]int, label string) string {
+		if _, ok := m[label]; ok {
+			return sourceConfig
+		}
+		return sourceDefault
+	}
+	fmt.Fprintln(w)
+	for _, label := range sortedKeys(prLabelPriorities) {
+		fmt.Fprintf(w, "label-priority.pr.%-20s %-4d %s\n", label, prLabelPriorities[label], labelSource(s.File.LabelPriority.PR, label))
+	}
+	for _, label := range sortedKeys(issueLabelPriorities) {
+		fmt.Fprintf(w, "label-priority.issue.%-17s %-4d %s\n", label, issueLabelPriorities[label], labelSource(s.File.LabelPriority.Issue, label))
+	}
+	labels := make([]string, 0, len(labelColorAttributes))
+	for label := range labelColorAttributes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		source := sourceDefault
+		if _, ok := s.File.LabelColors[label]; ok {
+			source = sourceConfig
+		}
+		fmt.Fprintf(w, "label-colors.%-25s %-8s %s\n", label, colorName(labelColorAttributes[label]), source)
+	}
+
+	if len(s.File.Views) > 0 {
+		fmt.Fprintln(w)
+		names := make([]string, 0, len(s.File.Views))
+		for name := range s.File.Views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			view := s.File.Views[name]
+			keys := make([]string, 0, len(view))
+			for key := range view {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			parts := make([]string, len(keys))
+			for i, key := range keys {
+				parts[i] = key + "=" + string(view[key])
+			}
+			fmt.Fprintf(w, "views.%s  %s\n", name, strings.Join(parts, " "))
+		}
+	}
+}
+
+// loadCommandSettings loads the settings for subcommands, resolving the main
+// command's options as if none were given on the command line
+func loadCommandSettings() (*Settings, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	fs := flag.NewFlagSet("github-feed", flag.ContinueOnError)
+	registerFlags(fs)
+	s, err := loadSettings(fs, configDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyLabelSettings(); err != nil {
+		return nil, err
+	}
+	settings = s
+	return s, nil
+}
+
+// runConfigCommand implements "github-feed config show"
+func runConfigCommand(args []string) error {
+	if len(args) != 1 || args[0] != "show" {
+		return errors.New("usage: github-feed config show")
+	}
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+	writeSettings(os.Stdout, s)
+	return nil
+}
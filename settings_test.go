@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, configYAML, dotEnv string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if configYAML != "" {
+		if err := os.WriteFile(filepath.Join(dir, configFileName), []byte(configYAML), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dotEnv != "" {
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotEnv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	dir := writeConfigFiles(t, "", `# comment
+GITHUB_TOKEN=ghp_abc
+export GITHUB_USERNAME="jane doe"
+ALLOWED_REPOS='minio/*,*/docs'
+EXCLUDED_REPOS=minio/mint # noisy
+`)
+	values, err := loadEnvFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"GITHUB_TOKEN":    "ghp_abc",
+		"GITHUB_USERNAME": "jane doe",
+		"ALLOWED_REPOS":   "minio/*,*/docs",
+		"EXCLUDED_REPOS":  "minio/mint",
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	dir := writeConfigFiles(t, `time: 2w
+format: markdown
+group-by: repo
+sort: -created
+not-author: ["dependabot[bot]", "renovate[bot]"]
+username: from-config
+`, "GITHUB_USERNAME=from-dotenv\nGITHUB_FEED_SORT=number\n")
+	t.Setenv("GITHUB_FEED_FORMAT", "html")
+	t.Setenv("GITHUB_USERNAME", "")
+	t.Setenv("GITHUB_USER", "")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := registerFlags(fs)
+	if err := fs.Parse([]string{"--time", "3d"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadSettings(fs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, source string
+		got                string
+	}{
+		{"time", "3d", sourceFlag, opts.timeRangeStr},
+		{"format", "html", sourceEnv, opts.outputFormat},
+		{"sort", "number", sourceDotEnv, opts.sortFlag},
+		{"group-by", "repo", sourceConfig, opts.groupBy},
+		{"not-author", "dependabot[bot],renovate[bot]", sourceConfig, opts.notAuthorFlag},
+		{"out", "", sourceDefault, opts.outputPath},
+	}
+	for _, tt := range tests {
+		r := s.values[tt.key]
+		if r.Value != tt.value || r.Source != tt.source {
+			t.Errorf("%s = %q from %s, want %q from %s", tt.key, r.Value, r.Source, tt.value, tt.source)
+		}
+		if tt.got != tt.value {
+			t.Errorf("%s flag variable = %q, want %q", tt.key, tt.got, tt.value)
+		}
+	}
+	if r := s.values["username"]; r.Value != "from-dotenv" || r.Source != sourceDotEnv {
+		t.Errorf("username = %q from %s, want from-dotenv from .env", r.Value, r.Source)
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name, config, want string
+	}{
+		{"unknown option", "tyme: 1d\n", `unknown option "tyme"`},
+		{"token", "token: ghp_abc\n", "tokens are not read from config.yaml"},
+		{"unknown view option", "views:\n  mine:\n    colour: red\n", `view mine: colour: unknown option`},
+		{"invalid value", "db-wait: soon\n", "invalid db-wait from"},
+	}
+	for _, tt := range tests {
+		dir := writeConfigFiles(t, tt.config, "")
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		registerFlags(fs)
+		_, err := loadSettings(fs, dir)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: loadSettings() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestApplyLabelSettings(t *testing.T) {
+	savedPriority := prLabelPriorities["Mentioned"]
+	savedColor := labelColorAttributes["Mentioned"]
+	defer func() {
+		prLabelPriorities["Mentioned"] = savedPriority
+		labelColorAttributes["Mentioned"] = savedColor
+	}()
+
+	dir := writeConfigFiles(t, "label-priority:\n  pr:\n    Mentioned: 2\nlabel-colors:\n  Mentioned: hi-red\n", "")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs)
+	s, err := loadSettings(fs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.applyLabelSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := getPRLabelPriority("Mentioned"); got != 2 {
+		t.Errorf("getPRLabelPriority(Mentioned) = %d, want 2", got)
+	}
+	if got := colorName(getLabelAttribute("Mentioned")); got != "hi-red" {
+		t.Errorf("Mentioned color = %s, want hi-red", got)
+	}
+
+	s.File.LabelColors = map[string]string{"Mentioned": "chartreuse"}
+	if err := s.applyLabelSettings(); err == nil {
+		t.Error("applyLabelSettings() accepted an unknown color")
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+// timelinesBucket maps PR keys to their review timeline as used by
+// "stats reviews"
+var timelinesBucket = []byte("timelines")
+
+// timelineFetchWorkers bounds concurrent timeline requests
+const timelineFetchWorkers = 4
+
+// timelineVersion is bumped whenever compactTimeline keeps new events, so
+// cached timelines without them are fetched again
+const timelineVersion = 1
+
+// reviewEvent is one review request, removed request, submitted review or merge
+type reviewEvent struct {
+	Event string    `json:"event"` // review_requested, review_request_removed, reviewed or merged
+	User  string    `json:"user"`  // the requested reviewer, the reviewer or who merged
+	State string    `json:"state,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// prTimeline is the review part of a PR's timeline
+type prTimeline struct {
+	Version   int           `json:"version,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"` // the PR's updated_at when the timeline was fetched
+	Events    []reviewEvent `json:"events"`
+}
+
+// SavePRTimeline caches the review timeline of a PR
+func (d *Database) SavePRTimeline(owner, repo string, number int, timeline *prTimeline, debugMode bool) error {
+	return d.save(timelinesBucket, buildItemKey(owner, repo, number), timeline, debugMode, "timeline")
+}
+
+// GetPRTimeline returns the cached review timeline of a PR, or nil when there is none
+func (d *Database) GetPRTimeline(owner, repo string, number int) (*prTimeline, error) {
+	var timeline *prTimeline
+	err := d.db.View(func(tx *bolt.Tx) error {
+		// Read-only databases created before timelines were cached lack the bucket
+		b := tx.Bucket(timelinesBucket)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(buildItemKey(owner, repo, number)))
+		if data == nil {
+			return nil
+		}
+		timeline = &prTimeline{}
+		return json.Unmarshal(data, timeline)
+	})
+	return timeline, err
+}
+
+// compactTimeline keeps the review and merge events of a timeline, oldest
+// first. Team review requests are dropped since reviews are submitted by people.
+func compactTimeline(events []*github.Timeline) []reviewEvent {
+	var compact []reviewEvent
+	for _, e := range events {
+		switch event := e.GetEvent(); event {
+		case "review_requested", "review_request_removed":
+			if e.Reviewer == nil {
+				continue
+			}
+			compact = append(compact, reviewEvent{Event: event, User: e.Reviewer.GetLogin(), At: e.GetCreatedAt().Time})
+		case "reviewed":
+			state := strings.ToLower(e.GetState())
+			if state == "pending" || e.SubmittedAt == nil {
+				continue
+			}
+			compact = append(compact, reviewEvent{Event: event, User: e.User.GetLogin(), State: state, At: e.SubmittedAt.Time})
+		case "merged":
+			// Search results carry no merged_at, so the timeline is where merges show up
+			compact = append(compact, reviewEvent{Event: event, User: e.Actor.GetLogin(), At: e.GetCreatedAt().Time})
+		}
+	}
+	sort.SliceStable(compact, func(i, j int) bool { return compact[i].At.Before(compact[j].At) })
+	return compact
+}
+
+// fetchPRTimeline fetches all timeline pages of a PR
+func fetchPRTimeline(owner, repo string, number int) ([]reviewEvent, error) {
+	var all []*github.Timeline
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		var events []*github.Timeline
+		var resp *github.Response
+		err := retryWithBackoff(func() error {
+			var err error
+			events, resp, err = config.client.Issues.ListIssueTimeline(config.ctx, owner, repo, number, opts)
+			return err
+		}, fmt.Sprintf("Timeline-PR#%d-page%d", number, opts.Page))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, events...)
+		if resp.NextPage == 0 {
+			return compactTimeline(all), nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// loadPRTimelines returns the review timelines of activities by item key. Cached
+// timelines are used while the PR has not changed since; with --local only the
+// cache is read and PRs without a timeline are left out.
+func loadPRTimelines(db *Database, activities []PRActivity) map[string][]reviewEvent {
+	timelines := make(map[string][]reviewEvent)
+	var stale []PRActivity
+	for _, activity := range activities {
+		key := buildItemKey(activity.Owner, activity.Repo, activity.PR.GetNumber())
+		var cached *prTimeline
+		if db != nil {
+			var err error
+			if cached, err = db.GetPRTimeline(activity.Owner, activity.Repo, activity.PR.GetNumber()); err != nil && config.debugMode {
+				fmt.Printf("  [DB] Warning: Failed to read the timeline of %s: %v\n", key, err)
+			}
+		}
+		fresh := cached != nil && cached.Version == timelineVersion && !cached.UpdatedAt.Before(activity.PR.GetUpdatedAt().Time)
+		if cached != nil && (config.localMode || fresh) {
+			timelines[key] = cached.Events
+			continue
+		}
+		if !config.localMode {
+			stale = append(stale, activity)
+		}
+	}
+	if len(stale) == 0 {
+		return timelines
+	}
+
+	config.progress = &Progress{hidden: !isTerminal(config.statusOut)}
+	config.progress.total.Store(int32(len(stale)))
+	if !config.progress.hidden && !config.debugMode {
+		fmt.Fprint(config.statusOut, "Fetching review timelines... ")
+		config.progress.display()
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan PRActivity)
+	for i := 0; i < timelineFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for activity := range queue {
+				number := activity.PR.GetNumber()
+				key := buildItemKey(activity.Owner, activity.Repo, number)
+				events, err := fetchPRTimeline(activity.Owner, activity.Repo, number)
+				config.progress.increment()
+				if !config.debugMode {
+					config.progress.display()
+				}
+				if err != nil {
+					if config.debugMode {
+						fmt.Printf("  Warning: Could not fetch the timeline of %s: %v\n", key, err)
+					}
+					continue
+				}
+				mu.Lock()
+				timelines[key] = events
+				mu.Unlock()
+				if db != nil && !db.readOnly {
+					timeline := &prTimeline{Version: timelineVersion, UpdatedAt: activity.PR.GetUpdatedAt().Time, Events: events}
+					if err := db.SavePRTimeline(activity.Owner, activity.Repo, number, timeline, config.debugMode); err != nil {
+						config.dbErrorCount.Add(1)
+						if config.debugMode {
+							fmt.Printf("  [DB] Warning: Failed to save the timeline of %s: %v\n", key, err)
+						}
+					}
+				}
+			}
+		}()
+	}
+	for _, activity := range stale {
+		queue <- activity
+	}
+	close(queue)
+	wg.Wait()
+
+	if !config.progress.hidden && !config.debugMode {
+		fmt.Fprint(config.statusOut, "\r"+strings.Repeat(" ", 80)+"\r")
+	}
+	return timelines
+}
+
+// prReviewStats is what one PR contributes to the statistics
+type prReviewStats struct {
+	firstReviews map[string]time.Duration // by reviewer: their first answered request to its review
+	rounds       map[string]int           // by reviewer
+	waiting      map[string]bool          // reviewers with an unanswered request
+}
+
+// analyzePRReviews pairs each review request with the reviewer's next review.
+// Only a reviewer's first answered request counts toward the time to first
+// review, later rounds measure iteration instead. Every answered request is a
+// review round, so re-requesting a review starts a
+// new one; a review without a request counts as one round. Reviews by the PR
+// author, such as replies to review comments, are ignored.
+func analyzePRReviews(author string, events []reviewEvent) prReviewStats {
+	stats := prReviewStats{
+		firstReviews: make(map[string]time.Duration),
+		rounds:       make(map[string]int),
+		waiting:      make(map[string]bool),
+	}
+	requested := make(map[string]time.Time)
+	for _, e := range events {
+		if e.User == "" || strings.EqualFold(e.User, author) {
+			continue
+		}
+		switch e.Event {
+		case "review_requested":
+			// A repeated request while one is open keeps the earlier start
+			if _, open := requested[e.User]; !open {
+				requested[e.User] = e.At
+			}
+		case "review_request_removed":
+			delete(requested, e.User)
+		case "reviewed":
+			if start, open := requested[e.User]; open {
+				if _, seen := stats.firstReviews[e.User]; !seen {
+					stats.firstReviews[e.User] = e.At.Sub(start)
+				}
+				stats.rounds[e.User]++
+				delete(requested, e.User)
+			} else if stats.rounds[e.User] == 0 {
+				stats.rounds[e.User] = 1
+			}
+		}
+	}
+	for reviewer := range requested {
+		stats.waiting[reviewer] = true
+	}
+	return stats
+}
+
+// mergedAt returns when the PR was merged, taken from the last merged event of
+// its timeline or from merged_at when a webhook filled it in
+func mergedAt(pr *github.PullRequest, events []reviewEvent) (time.Time, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Event == "merged" {
+			return events[i].At, true
+		}
+	}
+	if pr.MergedAt != nil {
+		return pr.MergedAt.Time, true
+	}
+	return time.Time{}, false
+}
+
+// percentiles summarizes a set of values with the nearest-rank p50 and p90
+type percentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+}
+
+func newPercentiles(values []float64) percentiles {
+	if len(values) == 0 {
+		return percentiles{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return percentiles{Count: len(sorted), P50: rank(0.5), P90: rank(0.9)}
+}
+
+// reviewStatsRow holds the statistics of one repo or reviewer. Durations are
+// in seconds.
+type reviewStatsRow struct {
+	Name        string       `json:"name"`
+	PRs         int          `json:"prs"`
+	FirstReview percentiles  `json:"first_review_seconds"`
+	Merge       *percentiles `json:"merge_seconds,omitempty"`
+	Rounds      percentiles  `json:"rounds"`
+	Waiting     int          `json:"waiting"`
+}
+
+// reviewStatsReport is the output of "stats reviews"
+type reviewStatsReport struct {
+	Since     time.Time        `json:"since"`
+	Until     *time.Time       `json:"until,omitempty"`
+	PRs       int              `json:"prs"`
+	Missing   int              `json:"missing_timelines"`
+	Repos     []reviewStatsRow `json:"repos"`
+	Reviewers []reviewStatsRow `json:"reviewers"`
+}
+
+// reviewStatsGroup collects the raw values of one row
+type reviewStatsGroup struct {
+	prs         int
+	firstReview []float64
+	merge       []float64
+	rounds      []float64
+	waiting     int
+}
+
+func (g *reviewStatsGroup) row(name string, withMerge bool) reviewStatsRow {
+	row := reviewStatsRow{
+		Name:        name,
+		PRs:         g.prs,
+		FirstReview: newPercentiles(g.firstReview),
+		Rounds:      newPercentiles(g.rounds),
+		Waiting:     g.waiting,
+	}
+	if withMerge {
+		merge := newPercentiles(g.merge)
+		row.Merge = &merge
+	}
+	return row
+}
+
+// buildReviewStats computes per-repo and per-reviewer statistics for the PRs
+// that have a timeline
+func buildReviewStats(activities []PRActivity, timelines map[string][]reviewEvent, window TimeWindow) reviewStatsReport {
+	report := reviewStatsReport{Since: window.Since}
+	if !window.Until.IsZero() {
+		until := window.Until
+		report.Until = &until
+	}
+	repos := make(map[string]*reviewStatsGroup)
+	reviewers := make(map[string]*reviewStatsGroup)
+	group := func(groups map[string]*reviewStatsGroup, name string) *reviewStatsGroup {
+		if groups[name] == nil {
+			groups[name] = &reviewStatsGroup{}
+		}
+		return groups[name]
+	}
+
+	for _, activity := range activities {
+		pr := activity.PR
+		events, ok := timelines[buildItemKey(activity.Owner, activity.Repo, pr.GetNumber())]
+		if !ok {
+			report.Missing++
+			continue
+		}
+		report.PRs++
+		repo := group(repos, activity.Owner+"/"+activity.Repo)
+		repo.prs++
+		if merged, ok := mergedAt(pr, events); ok && pr.CreatedAt != nil {
+			repo.merge = append(repo.merge, merged.Sub(pr.CreatedAt.Time).Seconds())
+		}
+
+		stats := analyzePRReviews(pr.User.GetLogin(), events)
+		involved := make(map[string]bool)
+		maxRounds := 0
+		for reviewer, d := range stats.firstReviews {
+			involved[reviewer] = true
+			repo.firstReview = append(repo.firstReview, d.Seconds())
+			group(reviewers, reviewer).firstReview = append(group(reviewers, reviewer).firstReview, d.Seconds())
+		}
+		for reviewer, rounds := range stats.rounds {
+			involved[reviewer] = true
+			group(reviewers, reviewer).rounds = append(group(reviewers, reviewer).rounds, float64(rounds))
+			if rounds > maxRounds {
+				maxRounds = rounds
+			}
+		}
+		for reviewer := range stats.waiting {
+			involved[reviewer] = true
+			repo.waiting++
+			group(reviewers, reviewer).waiting++
+		}
+		for reviewer := range involved {
+			group(reviewers, reviewer).prs++
+		}
+		// A PR takes as many rounds as its most iterated reviewer
+		if maxRounds > 0 {
+			repo.rounds = append(repo.rounds, float64(maxRounds))
+		}
+	}
+
+	rows := func(groups map[string]*reviewStatsGroup, withMerge bool) []reviewStatsRow {
+		result := make([]reviewStatsRow, 0, len(groups))
+		for name, g := range groups {
+			result = append(result, g.row(name, withMerge))
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].PRs != result[j].PRs {
+				return result[i].PRs > result[j].PRs
+			}
+			return result[i].Name < result[j].Name
+		})
+		return result
+	}
+	report.Repos = rows(repos, true)
+	report.Reviewers = rows(reviewers, false)
+	return report
+}
+
+// shortDuration formats seconds as "45m", "5.5h" or "3.2d"
+func shortDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Round(time.Minute).Minutes()))
+	case d < 24*time.Hour:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", d.Hours()), ".0") + "h"
+	default:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", d.Hours()/24), ".0") + "d"
+	}
+}
+
+// percentileCells formats p50 and p90 as two table cells, "-" without values
+func percentileCells(p percentiles, format func(float64) string) string {
+	if p.Count == 0 {
+		return "-\t-"
+	}
+	return format(p.P50) + "\t" + format(p.P90)
+}
+
+func writeReviewStatsTable(w io.Writer, report reviewStatsReport) error {
+	rounds := func(v float64) string { return fmt.Sprintf("%g", v) }
+	period := "since " + report.Since.Local().Format("2006-01-02")
+	if report.Until != nil {
+		period += " until " + report.Until.Local().Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "Review turnaround for %d PRs updated %s\n", report.PRs, period)
+	if report.Missing > 0 {
+		hint := ""
+		if config.localMode {
+			hint = " (run without --local to fetch them)"
+		}
+		fmt.Fprintf(w, "%d PR(s) without a review timeline were skipped%s\n", report.Missing, hint)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nREPO\tPRS\tREVIEW p50\tp90\tMERGE p50\tp90\tROUNDS p50\tp90\tWAITING")
+	for _, row := range report.Repos {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\n", row.Name, row.PRs,
+			percentileCells(row.FirstReview, shortDuration), percentileCells(*row.Merge, shortDuration),
+			percentileCells(row.Rounds, rounds), row.Waiting)
+	}
+	fmt.Fprintln(tw, "\nREVIEWER\tPRS\tREVIEW p50\tp90\tROUNDS p50\tp90\tWAITING")
+	for _, row := range report.Reviewers {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\n", row.Name, row.PRs,
+			percentileCells(row.FirstReview, shortDuration), percentileCells(row.Rounds, rounds), row.Waiting)
+	}
+	return tw.Flush()
+}
+
+// runStatsCommand implements "github-feed stats reviews"
+func runStatsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "reviews" {
+		return errors.New("usage: github-feed stats reviews [--time 3m] [--format table|json] [flags]")
+	}
+	fs := flag.NewFlagSet("stats reviews", flag.ExitOnError)
+	opts := registerFlags(fs)
+	fs.Parse(args[1:])
+
+	// --format picks the stats output here; the feed formats don't apply
+	format := "table"
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "format" {
+			format = opts.outputFormat
+		}
+	})
+	if format == "text" {
+		format = "table"
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid --format %q for stats (use table or json)", format)
+	}
+	opts.outputFormat = "text"
+	opts.outputPath = ""
+
+	db := setupFeed(fs, opts)
+	if db == nil {
+		return errors.New("stats need the database cache; run github-feed first to fill it")
+	}
+	defer db.Close()
+	if format == "json" {
+		config.statusOut = os.Stderr
+	}
+
+	activities, _, err := cachedActivity(db, config.window)
+	if err != nil {
+		return err
+	}
+
+	report := buildReviewStats(activities, loadPRTimelines(db, activities), config.window)
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeReviewStatsTable(os.Stdout, report)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestCompactTimeline(t *testing.T) {
+	at := func(h int) *github.Timestamp {
+		return &github.Timestamp{Time: time.Date(2026, 10, 1, h, 0, 0, 0, time.UTC)}
+	}
+	user := func(login string) *github.User { return &github.User{Login: github.String(login)} }
+	events := compactTimeline([]*github.Timeline{
+		{Event: github.String("reviewed"), User: user("alice"), State: github.String("APPROVED"), SubmittedAt: at(5)},
+		{Event: github.String("review_requested"), Reviewer: user("alice"), CreatedAt: at(1)},
+		{Event: github.String("review_requested"), RequestedTeam: &github.Team{Slug: github.String("core")}, CreatedAt: at(1)},
+		{Event: github.String("labeled"), CreatedAt: at(2)},
+		{Event: github.String("reviewed"), User: user("bob"), State: github.String("PENDING")},
+		{Event: github.String("merged"), Actor: user("carol"), CreatedAt: at(6)},
+	})
+	if len(events) != 3 || events[0].Event != "review_requested" || events[1].State != "approved" || events[1].User != "alice" ||
+		events[2].Event != "merged" || events[2].User != "carol" || !events[2].At.Equal(at(6).Time) {
+		t.Errorf("compactTimeline() = %+v, want alice's request, her approval, then carol's merge", events)
+	}
+}
+
+func TestAnalyzePRReviews(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	stats := analyzePRReviews("author", []reviewEvent{
+		{Event: "review_requested", User: "alice", At: at(0)},
+		{Event: "review_requested", User: "bob", At: at(0)},
+		{Event: "reviewed", User: "alice", State: "changes_requested", At: at(2)},
+		{Event: "reviewed", User: "author", State: "commented", At: at(3)},
+		{Event: "reviewed", User: "alice", State: "commented", At: at(3)},
+		{Event: "review_requested", User: "alice", At: at(4)},
+		{Event: "reviewed", User: "alice", State: "approved", At: at(10)},
+		{Event: "reviewed", User: "carol", State: "commented", At: at(11)},
+		{Event: "review_request_removed", User: "bob", At: at(12)},
+		{Event: "review_requested", User: "dave", At: at(12)},
+	})
+
+	if got, ok := stats.firstReviews["alice"]; !ok || got != 2*time.Hour || len(stats.firstReviews) != 1 {
+		t.Errorf("first reviews = %v, want only alice's first round of 2h", stats.firstReviews)
+	}
+	if stats.rounds["alice"] != 2 || stats.rounds["carol"] != 1 || stats.rounds["author"] != 0 {
+		t.Errorf("rounds = %v, want alice 2, carol 1 and none for the author", stats.rounds)
+	}
+	if len(stats.waiting) != 1 || !stats.waiting["dave"] {
+		t.Errorf("waiting = %v, want only dave", stats.waiting)
+	}
+}
+
+func TestNewPercentiles(t *testing.T) {
+	if p := newPercentiles(nil); p.Count != 0 {
+		t.Errorf("newPercentiles(nil) = %+v", p)
+	}
+	p := newPercentiles([]float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5})
+	if p.Count != 10 || p.P50 != 5 || p.P90 != 9 {
+		t.Errorf("newPercentiles(1..10) = %+v, want p50 5 and p90 9", p)
+	}
+	if p := newPercentiles([]float64{42}); p.P50 != 42 || p.P90 != 42 {
+		t.Errorf("newPercentiles([42]) = %+v", p)
+	}
+}
+
+func TestBuildReviewStats(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	ts := func(h int) *github.Timestamp { return &github.Timestamp{Time: start.Add(time.Duration(h) * time.Hour)} }
+	// PRs come from search results, which carry no merged_at
+	pr := func(number int) PRActivity {
+		p := prFromIssue(&github.Issue{Number: github.Int(number), User: &github.User{Login: github.String("author")},
+			State: github.String("closed"), CreatedAt: ts(0), UpdatedAt: ts(48), ClosedAt: ts(24),
+			PullRequestLinks: &github.PullRequestLinks{URL: github.String("https://api.github.com/repos/acme/app/pulls/1")}})
+		return PRActivity{Owner: "acme", Repo: "app", PR: p}
+	}
+	activities := []PRActivity{pr(1), pr(2), pr(3)}
+
+	timeline := &prTimeline{Version: timelineVersion, UpdatedAt: ts(48).Time, Events: []reviewEvent{
+		{Event: "review_requested", User: "alice", At: ts(1).Time},
+		{Event: "reviewed", User: "alice", State: "approved", At: ts(4).Time},
+		{Event: "merged", User: "alice", At: ts(24).Time},
+	}}
+	if err := db.SavePRTimeline("acme", "app", 1, timeline, false); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := db.GetPRTimeline("acme", "app", 1)
+	if err != nil || cached == nil || len(cached.Events) != 3 || !cached.UpdatedAt.Equal(ts(48).Time) {
+		t.Fatalf("GetPRTimeline() = %+v, %v", cached, err)
+	}
+	if missing, err := db.GetPRTimeline("acme", "app", 3); err != nil || missing != nil {
+		t.Errorf("GetPRTimeline() for an uncached PR = %+v, %v, want nil", missing, err)
+	}
+
+	timelines := map[string][]reviewEvent{
+		"acme/app#1": cached.Events,
+		"acme/app#2": {{Event: "review_requested", User: "bob", At: ts(2).Time}},
+	}
+	report := buildReviewStats(activities, timelines, TimeWindow{Since: start})
+	if report.PRs != 2 || report.Missing != 1 || len(report.Repos) != 1 {
+		t.Fatalf("report = %+v, want 2 PRs in one repo and one missing timeline", report)
+	}
+	repo := report.Repos[0]
+	if repo.Name != "acme/app" || repo.FirstReview.P50 != 3*3600 || repo.Merge.Count != 1 || repo.Merge.P90 != 24*3600 || repo.Waiting != 1 {
+		t.Errorf("repo row = %+v", repo)
+	}
+	if len(report.Reviewers) != 2 || report.Reviewers[0].Name != "alice" || report.Reviewers[0].Rounds.P50 != 1 || report.Reviewers[1].Waiting != 1 {
+		t.Errorf("reviewer rows = %+v", report.Reviewers)
+	}
+
+	var out bytes.Buffer
+	if err := writeReviewStatsTable(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Review turnaround for 2 PRs", "1 PR(s) without a review timeline", "acme/app", "3h", "1d", "alice"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table missing %q:\n%s", want, out.String())
+		}
+	}
+}
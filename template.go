@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// TemplateItem is the data a --template is rendered against, once per PR or issue line.
+//
+//	.Kind          "pr" or "issue"
+//	.Label         feed label, e.g. "Review Requested"
+//	.State         "open", "closed" or "merged"
+//	.User          author login
+//	.Owner, .Name  repository owner and name
+//	.Repo          "owner/name"
+//	.Number        PR or issue number
+//	.Title         title
+//	.URL           link to the PR or issue on GitHub
+//	.Updated       last update time (time.Time)
+//	.HasUpdates    true when the "●" update marker would show
+//	.Nested        true for issues shown under their linked PR
+//	.LinkedIssues  issues linked to a PR (each is printed as its own nested line as well)
+//	.GHLabels      GitHub label names
+//	.Chips         GitHub labels rendered as colored chips
+//	.Milestone     milestone title, if any
+type TemplateItem struct {
+	Kind         string
+	Label        string
+	State        string
+	User         string
+	Owner        string
+	Name         string
+	Repo         string
+	Number       int
+	Title        string
+	URL          string
+	Updated      time.Time
+	HasUpdates   bool
+	Nested       bool
+	LinkedIssues []TemplateItem
+	GHLabels     []string
+	Chips        string
+	Milestone    string
+}
+
+// builtinTemplates are selectable by name with --template
+var builtinTemplates = map[string]string{
+	"compact": `{{marker .HasUpdates}}{{if .Nested}}  -- {{end}}{{label .Label}} {{.Repo}}#{{.Number}} {{truncate 60 .Title}}`,
+	"wide":    `{{marker .HasUpdates}}{{if .Nested}}   -- {{end}}{{date .Updated}} {{pad 8 (ago .Updated)}} {{pad 16 (label .Label)}} {{pad 6 (state .State)}} {{pad 18 (user .User)}} {{.Repo}}#{{.Number}} - {{.Title}}`,
+}
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;[^\x1b]*\x1b\\\\")
+
+// visibleWidth returns the number of terminal columns s takes, ignoring color escape codes
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// truncateText shortens s to at most width runes, ending in "…" when cut
+func truncateText(width int, s string) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// relativeTime formats t as a short age such as "5m ago", "3h ago" or "2w ago"
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 60*24*time.Hour:
+		return fmt.Sprintf("%dw ago", int(d.Hours()/24/7))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+	}
+}
+
+// namedColors are the color names accepted by the "color" template function
+// and the label-colors section of config.yaml
+var namedColors = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+	"gray":    color.FgHiBlack,
+	"bold":    color.Bold,
+
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+}
+
+var templateFuncs = template.FuncMap{
+	"label": func(label string) string { return getLabelColor(label).Sprint(strings.ToUpper(label)) },
+	"state": func(state string) string { return getStateColor(state).Sprint(strings.ToUpper(state)) },
+	"user":  func(user string) string { return getUserColor(user).Sprint(user) },
+	"color": func(name string, text string) string {
+		if attr, ok := namedColors[name]; ok {
+			return color.New(attr).Sprint(text)
+		}
+		return text
+	},
+	"marker": func(hasUpdates bool) string {
+		if hasUpdates {
+			return color.New(color.FgYellow, color.Bold).Sprint("● ")
+		}
+		return ""
+	},
+	"ago":      relativeTime,
+	"date":     func(t time.Time) string { return t.Format("2006/01/02") },
+	"truncate": truncateText,
+	"pad": func(width int, s string) string {
+		if n := visibleWidth(s); n < width {
+			return s + strings.Repeat(" ", width-n)
+		}
+		return s
+	},
+	"link": func(url, text string) string {
+		if config.hyperlinks && url != "" {
+			return hyperlink(url, text)
+		}
+		return text
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// loadItemTemplate resolves --template: a built-in name, a file in
+// <configDir>/templates/NAME.tmpl, or an inline template
+func loadItemTemplate(value, configDir string) (*template.Template, error) {
+	text, ok := builtinTemplates[value]
+	if !ok {
+		path := filepath.Join(configDir, "templates", value+".tmpl")
+		if data, err := os.ReadFile(path); err == nil {
+			text = string(data)
+		} else if strings.Contains(value, "{{") {
+			text = value
+		} else {
+			return nil, fmt.Errorf("unknown template %q (use compact, wide, a file in %s, or an inline Go template)",
+				value, filepath.Join(configDir, "templates"))
+		}
+	}
+
+	tmpl, err := template.New("item").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func newTemplateItem(cfg DisplayConfig) TemplateItem {
+	item := TemplateItem{
+		Kind:       cfg.Kind,
+		Label:      cfg.Label,
+		User:       cfg.User,
+		Owner:      cfg.Owner,
+		Name:       cfg.Repo,
+		Repo:       cfg.Owner + "/" + cfg.Repo,
+		Number:     cfg.Number,
+		Title:      cfg.Title,
+		HasUpdates: cfg.HasUpdates,
+		Nested:     cfg.IsIndented,
+		Chips:      labelChips(cfg.GHLabels),
+		Milestone:  cfg.Milestone,
+	}
+	for _, label := range cfg.GHLabels {
+		item.GHLabels = append(item.GHLabels, label.GetName())
+	}
+	if cfg.State != nil {
+		item.State = *cfg.State
+	}
+	if cfg.HTMLURL != nil {
+		item.URL = *cfg.HTMLURL
+	}
+	if cfg.UpdatedAt != nil {
+		item.Updated = cfg.UpdatedAt.Time
+	}
+	for _, issue := range cfg.Linked {
+		item.LinkedIssues = append(item.LinkedIssues, newTemplateItem(DisplayConfig{
+			Owner:      issue.Owner,
+			Repo:       issue.Repo,
+			Number:     issue.Issue.GetNumber(),
+			Title:      issue.Issue.GetTitle(),
+			User:       issue.Issue.User.GetLogin(),
+			UpdatedAt:  issue.Issue.UpdatedAt,
+			HTMLURL:    issue.Issue.HTMLURL,
+			Label:      issue.Label,
+			HasUpdates: issue.HasUpdates,
+			IsIndented: true,
+			State:      issue.Issue.State,
+			Kind:       "issue",
+			GHLabels:   issue.Issue.Labels,
+			Milestone:  issue.Issue.Milestone.GetTitle(),
+		}))
+	}
+	return item
+}
+
+// displayTemplateItem renders one item line with config.itemTemplate
+func displayTemplateItem(w io.Writer, cfg DisplayConfig) {
+	item := newTemplateItem(cfg)
+
+	var buf bytes.Buffer
+	if err := config.itemTemplate.Execute(&buf, item); err != nil {
+		fmt.Fprintf(w, "template error for %s#%d: %v\n", item.Repo, item.Number, err)
+		return
+	}
+	fmt.Fprintln(w, strings.TrimRight(buf.String(), "\n"))
+
+	if config.showLinks && item.URL != "" {
+		indent := "   "
+		if item.Nested {
+			indent = "      "
+		}
+		fmt.Fprintf(w, "%s🔗 %s\n", indent, item.URL)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// setNoColor sets color.NoColor for the rest of the test and restores it afterwards
+func setNoColor(t *testing.T, noColor bool) {
+	t.Helper()
+	saved := color.NoColor
+	t.Cleanup(func() { color.NoColor = saved })
+	color.NoColor = noColor
+}
+
+func TestLoadItemTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "mine.tmpl"), []byte("{{.Repo}}#{{.Number}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setNoColor(t, true)
+	item := TemplateItem{Label: "Authored", Repo: "o/r", Number: 7, Title: "A very long title"}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "compact", want: "AUTHORED o/r#7 A very long title"},
+		{value: "mine", want: "o/r#7\n"},
+		{value: "{{upper .Repo}} {{truncate 6 .Title}}", want: "O/R A ver…"},
+		{value: "missing", wantErr: true},
+		{value: "{{.Nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		tmpl, err := loadItemTemplate(tt.value, dir)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("loadItemTemplate(%q) succeeded, want error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("loadItemTemplate(%q) error: %v", tt.value, err)
+			continue
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, item); err != nil {
+			t.Errorf("executing %q: %v", tt.value, err)
+			continue
+		}
+		if sb.String() != tt.want {
+			t.Errorf("template %q = %q, want %q", tt.value, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{2 * 24 * time.Hour, "2d ago"},
+		{21 * 24 * time.Hour, "3w ago"},
+		{400 * 24 * time.Hour, "1y ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(time.Now().Add(-tt.ago)); got != tt.want {
+			t.Errorf("relativeTime(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"plain", 5},
+		{"\x1b[36mAUTHORED\x1b[0m", 8},
+		{hyperlink("https://github.com/o/r/pull/1", "o/r#1"), 5},
+		{"● café", 6},
+	}
+	for _, tt := range tests {
+		if got := visibleWidth(tt.text); got != tt.want {
+			t.Errorf("visibleWidth(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
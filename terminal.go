@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// terminalWidth returns the width of the terminal on stdout, honoring $COLUMNS,
+// or 0 when stdout is not a terminal or the width is unknown
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if !isTerminal(os.Stdout) {
+		return 0
+	}
+	return stdoutWidth()
+}
+
+// supportsHyperlinks reports whether the terminal on stdout is known to render
+// OSC 8 hyperlinks. GITHUB_FEED_HYPERLINKS=1 or 0 overrides the detection.
+func supportsHyperlinks() bool {
+	switch os.Getenv("GITHUB_FEED_HYPERLINKS") {
+	case "1", "true", "yes":
+		return true
+	case "0", "false", "no":
+		return false
+	}
+
+	if !isTerminal(os.Stdout) || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	term := os.Getenv("TERM")
+	for _, name := range []string{"kitty", "alacritty", "foot", "ghostty", "wezterm"} {
+		if strings.Contains(term, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlink wraps text in an OSC 8 escape sequence pointing at url
+func hyperlink(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeWindow is the range of updated_at times to show. Until is exclusive and
+// zero means "up to now".
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+}
+
+// contains reports whether t falls inside the window
+func (w TimeWindow) contains(t time.Time) bool {
+	if t.Before(w.Since) {
+		return false
+	}
+	return w.Until.IsZero() || t.Before(w.Until)
+}
+
+// searchQualifier returns the GitHub search qualifier matching the window
+func (w TimeWindow) searchQualifier() string {
+	const layout = "2006-01-02T15:04:05Z"
+	since := w.Since.UTC().Format(layout)
+	if w.Until.IsZero() {
+		return "updated:>=" + since
+	}
+	// Ranges in search are inclusive, Until is not
+	return fmt.Sprintf("updated:%s..%s", since, w.Until.Add(-time.Second).UTC().Format(layout))
+}
+
+func (w TimeWindow) String() string {
+	const layout = "2006-01-02 15:04"
+	if w.Until.IsZero() {
+		return "since " + w.Since.Format(layout)
+	}
+	return fmt.Sprintf("from %s until %s", w.Since.Format(layout), w.Until.Format(layout))
+}
+
+// namedRanges lists the values --time accepts besides durations
+var namedRanges = []string{"today", "yesterday", "this-week", "last-week", "this-month", "last-month", "this-sprint", "last-sprint"}
+
+// defaultSprintDays is the sprint length used when sprint-days is not set
+const defaultSprintDays = 14
+
+// sprintSettings reads the sprint-days setting and the optional sprint-start
+// anchor date (the first day of any sprint)
+func sprintSettings() (days int, anchor time.Time, err error) {
+	days = defaultSprintDays
+	if value := settingValue("sprint-days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 {
+			return 0, time.Time{}, fmt.Errorf("invalid sprint-days %q (must be a positive number of days)", value)
+		}
+	}
+	if value := settingValue("sprint-start"); value != "" {
+		anchor, err = parseDate(value)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid sprint-start: %w", err)
+		}
+	}
+	return days, anchor, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts calendar days from a to b, so days that are 23 or 25 hours
+// long around DST changes still count as one
+func daysBetween(a, b time.Time) int {
+	dateA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dateB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dateB.Sub(dateA).Hours() / 24)
+}
+
+// startOfWeek returns midnight on the Monday of t's week
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
+
+// parseDate accepts YYYY-MM-DD (local midnight) or an RFC3339 timestamp
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC3339)", value)
+}
+
+// namedRange resolves one of namedRanges relative to now
+func namedRange(name string, now time.Time) (TimeWindow, bool, error) {
+	today := startOfDay(now)
+	switch name {
+	case "today":
+		return TimeWindow{Since: today}, true, nil
+	case "yesterday":
+		return TimeWindow{Since: today.AddDate(0, 0, -1), Until: today}, true, nil
+	case "this-week":
+		return TimeWindow{Since: startOfWeek(now)}, true, nil
+	case "last-week":
+		week := startOfWeek(now)
+		return TimeWindow{Since: week.AddDate(0, 0, -7), Until: week}, true, nil
+	case "this-month":
+		return TimeWindow{Since: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())}, true, nil
+	case "last-month":
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return TimeWindow{Since: month.AddDate(0, -1, 0), Until: month}, true, nil
+	case "this-sprint", "last-sprint":
+		days, anchor, err := sprintSettings()
+		if err != nil {
+			return TimeWindow{}, true, err
+		}
+		// Without an anchor, a sprint is simply the last sprint-days days
+		if anchor.IsZero() {
+			start := today.AddDate(0, 0, -days)
+			if name == "this-sprint" {
+				return TimeWindow{Since: start}, true, nil
+			}
+			return TimeWindow{Since: start.AddDate(0, 0, -days), Until: start}, true, nil
+		}
+		elapsed := daysBetween(anchor, today)
+		sprints := elapsed / days
+		if elapsed < 0 && elapsed%days != 0 {
+			sprints--
+		}
+		start := startOfDay(anchor).AddDate(0, 0, sprints*days)
+		if name == "this-sprint" {
+			return TimeWindow{Since: start}, true, nil
+		}
+		return TimeWindow{Since: start.AddDate(0, 0, -days), Until: start}, true, nil
+	}
+	return TimeWindow{}, false, nil
+}
+
+// subtractDuration goes back from now by a duration such as 1w3d or 2m, using
+// calendar months and years
+func subtractDuration(value string, now time.Time) (time.Time, error) {
+	invalid := fmt.Errorf("invalid time range %q (expected units like 1h, 2d, 3w, 4m, 1y, combined as 1w3d)", value)
+	if value == "" {
+		return time.Time{}, invalid
+	}
+
+	t := now
+	rest := value
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return time.Time{}, invalid
+		}
+		num, err := strconv.Atoi(rest[:i])
+		if err != nil || num < 1 {
+			return time.Time{}, invalid
+		}
+
+		switch rest[i] {
+		case 'h':
+			t = t.Add(-time.Duration(num) * time.Hour)
+		case 'd':
+			t = t.AddDate(0, 0, -num)
+		case 'w':
+			t = t.AddDate(0, 0, -7*num)
+		case 'm':
+			t = t.AddDate(0, -num, 0)
+		case 'y':
+			t = t.AddDate(-num, 0, 0)
+		default:
+			return time.Time{}, fmt.Errorf("invalid time unit %q in %q (use h=hours, d=days, w=weeks, m=months, y=years)", rest[i], value)
+		}
+		rest = rest[i+1:]
+	}
+	return t, nil
+}
+
+// parseTimeWindow combines --time, --since and --until. --since replaces
+// --time; --until accepts the same forms as --since and includes that whole day
+// when given as a plain date.
+func parseTimeWindow(timeStr, since, until string, now time.Time) (TimeWindow, error) {
+	var window TimeWindow
+
+	if since != "" {
+		t, err := parseDate(since)
+		if err != nil {
+			return TimeWindow{}, fmt.Errorf("--since: %w", err)
+		}
+		window.Since = t
+	} else {
+		name := strings.ToLower(timeStr)
+		named, ok, err := namedRange(name, now)
+		if err != nil {
+			return TimeWindow{}, err
+		}
+		if ok {
+			window = named
+		} else {
+			window.Since, err = subtractDuration(name, now)
+			if err != nil {
+				return TimeWindow{}, err
+			}
+		}
+	}
+
+	if until != "" {
+		t, err := parseDate(until)
+		if err != nil {
+			return TimeWindow{}, fmt.Errorf("--until: %w", err)
+		}
+		if !strings.Contains(until, "T") {
+			t = t.AddDate(0, 0, 1)
+		}
+		window.Until = t
+	}
+
+	if !window.Until.IsZero() && !window.Since.Before(window.Until) {
+		return TimeWindow{}, fmt.Errorf("empty time range: %s", window)
+	}
+	return window, nil
+}
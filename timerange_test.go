@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 9, 16, 15, 30, 0, 0, time.Local)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		time    string
+		since   string
+		until   string
+		want    TimeWindow
+		wantErr bool
+	}{
+		{time: "2h", want: TimeWindow{Since: now.Add(-2 * time.Hour)}},
+		{time: "1w3d", want: TimeWindow{Since: now.AddDate(0, 0, -10)}},
+		{time: "1m", want: TimeWindow{Since: time.Date(2026, 8, 16, 15, 30, 0, 0, time.Local)}},
+		{time: "1y2m", want: TimeWindow{Since: time.Date(2025, 7, 16, 15, 30, 0, 0, time.Local)}},
+		{time: "today", want: TimeWindow{Since: day(9, 16)}},
+		{time: "yesterday", want: TimeWindow{Since: day(9, 15), Until: day(9, 16)}},
+		{time: "this-week", want: TimeWindow{Since: day(9, 14)}},
+		{time: "last-week", want: TimeWindow{Since: day(9, 7), Until: day(9, 14)}},
+		{time: "last-month", want: TimeWindow{Since: day(8, 1), Until: day(9, 1)}},
+		{time: "1m", since: "2026-09-01", until: "2026-09-10", want: TimeWindow{Since: day(9, 1), Until: day(9, 11)}},
+		{time: "1w", until: "2026-09-14", want: TimeWindow{Since: now.AddDate(0, 0, -7), Until: day(9, 15)}},
+		{time: "3x", wantErr: true},
+		{time: "w", wantErr: true},
+		{time: "0d", wantErr: true},
+		{time: "1d", since: "yesterday", wantErr: true},
+		{time: "1d", since: "2026-09-10", until: "2026-09-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeWindow(tt.time, tt.since, tt.until, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeWindow(%q, %q, %q) error = %v, wantErr %v", tt.time, tt.since, tt.until, err, tt.wantErr)
+			continue
+		}
+		if !got.Since.Equal(tt.want.Since) || !got.Until.Equal(tt.want.Until) {
+			t.Errorf("parseTimeWindow(%q, %q, %q) = %v, want %v", tt.time, tt.since, tt.until, got, tt.want)
+		}
+	}
+}
+
+func TestSprintRanges(t *testing.T) {
+	t.Setenv("SPRINT_DAYS", "14")
+	t.Setenv("SPRINT_START", "2026-09-07")
+	now := time.Date(2026, 9, 30, 9, 0, 0, 0, time.Local)
+
+	this, err := parseTimeWindow("this-sprint", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 21, 0, 0, 0, 0, time.Local); !this.Since.Equal(want) {
+		t.Errorf("this-sprint since = %v, want %v", this.Since, want)
+	}
+
+	last, err := parseTimeWindow("last-sprint", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 7, 0, 0, 0, 0, time.Local); !last.Since.Equal(want) || !last.Until.Equal(this.Since) {
+		t.Errorf("last-sprint = %v, want since %v until %v", last, want, this.Since)
+	}
+
+	// Without an anchor the sprints are the last 14 days and the 14 before them
+	t.Setenv("SPRINT_START", "")
+	this, err = parseTimeWindow("this-sprint", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 16, 0, 0, 0, 0, time.Local); !this.Since.Equal(want) || !this.Until.IsZero() {
+		t.Errorf("unanchored this-sprint = %v, want since %v", this, want)
+	}
+	last, err = parseTimeWindow("last-sprint", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 2, 0, 0, 0, 0, time.Local); !last.Since.Equal(want) || !last.Until.Equal(this.Since) {
+		t.Errorf("unanchored last-sprint = %v, want since %v until %v", last, want, this.Since)
+	}
+}
+
+func TestSprintRangesAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	savedLocal := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = savedLocal })
+
+	// Clocks spring forward on 2026-03-08, making that day 23 hours long
+	t.Setenv("SPRINT_DAYS", "14")
+	t.Setenv("SPRINT_START", "2026-03-02")
+	this, err := parseTimeWindow("this-sprint", "", "", time.Date(2026, 3, 16, 9, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 16, 0, 0, 0, 0, loc); !this.Since.Equal(want) {
+		t.Errorf("this-sprint since = %v, want %v", this.Since, want)
+	}
+}
+
+func TestTimeWindowSearchQualifier(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	if got, want := (TimeWindow{Since: since}).searchQualifier(), "updated:>=2026-09-01T00:00:00Z"; got != want {
+		t.Errorf("searchQualifier() = %q, want %q", got, want)
+	}
+	window := TimeWindow{Since: since, Until: since.AddDate(0, 0, 30)}
+	if got, want := window.searchQualifier(), "updated:2026-09-01T00:00:00Z..2026-09-30T23:59:59Z"; got != want {
+		t.Errorf("searchQualifier() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// listOptions are the comma-separated options, saved as YAML lists
+var listOptions = map[string]bool{
+	"label": true, "gh-label": true, "exclude-gh-label": true,
+	"allowed-repos": true, "exclude-repos": true, "org": true,
+	"author": true, "not-author": true,
+}
+
+// reservedViewNames are view subcommands that can't be used as view names
+var reservedViewNames = map[string]bool{"list": true, "save": true}
+
+// viewArgs turns a view into flags, in a stable order
+func viewArgs(view map[string]configValue) []string {
+	keys := make([]string, 0, len(view))
+	for key := range view {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, "--"+key+"="+string(view[key]))
+	}
+	return args
+}
+
+// viewOption is one flag captured by view save
+type viewOption struct {
+	key   string
+	value string
+	bool  bool
+}
+
+// viewOptionNode returns the YAML value for a saved option
+func viewOptionNode(option viewOption) *yaml.Node {
+	if listOptions[option.key] {
+		list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range splitList(option.value) {
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+		return list
+	}
+	tag := "!!str"
+	if option.bool {
+		tag = "!!bool"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: option.value}
+}
+
+// mappingValue returns the value node for key in a YAML mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// saveViewToFile adds or replaces views.<name> in the config file, keeping the
+// rest of the file and its comments. It reports whether the view existed.
+func saveViewToFile(filename, name string, options []viewOption) (replaced bool, err error) {
+	var doc yaml.Node
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, fmt.Errorf("%s: %w", filename, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("%s: top level is not a mapping", filename)
+	}
+
+	views := mappingValue(root, "views")
+	if views == nil {
+		views = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "views"}, views)
+	}
+
+	view := &yaml.Node{Kind: yaml.MappingNode}
+	for _, option := range options {
+		view.Content = append(view.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: option.key}, viewOptionNode(option))
+	}
+
+	if existing := mappingValue(views, name); existing != nil {
+		*existing = *view
+		replaced = true
+	} else {
+		views.Content = append(views.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, view)
+	}
+
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return false, err
+	}
+	if err := encoder.Close(); err != nil {
+		return false, err
+	}
+	return replaced, os.WriteFile(filename, []byte(out.String()), 0o644)
+}
+
+// saveView captures the flags in args as views.<name> in config.yaml
+func saveView(name string, args []string) error {
+	if reservedViewNames[name] || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid view name %q", name)
+	}
+
+	fs := flag.NewFlagSet("view save", flag.ContinueOnError)
+	registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var options []viewOption
+	var visitErr error
+	fs.Visit(func(f *flag.Flag) {
+		if reason, ok := fileOnlyExcluded[f.Name]; ok {
+			visitErr = errors.New(reason)
+			return
+		}
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		options = append(options, viewOption{key: f.Name, value: f.Value.String(), bool: ok && boolFlag.IsBoolFlag()})
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	if len(options) == 0 {
+		return errors.New("no flags to save (usage: github-feed view save NAME --flag value ...)")
+	}
+
+	configDir, err := getConfigDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(configDir, configFileName)
+	replaced, err := saveViewToFile(path, name, options)
+	if err != nil {
+		return err
+	}
+	verb := "Saved"
+	if replaced {
+		verb = "Updated"
+	}
+	fmt.Printf("%s view %q in %s\n", verb, name, path)
+	return nil
+}
+
+// listViews prints each view with the flags it expands to
+func listViews(views map[string]map[string]configValue) {
+	if len(views) == 0 {
+		fmt.Println("No views defined (add them under views: in config.yaml, or use 'github-feed view save NAME --flags...')")
+		return
+	}
+	names := make([]string, 0, len(views))
+	width := 0
+	for name := range views {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args := viewArgs(views[name])
+		for i, arg := range args {
+			if strings.ContainsAny(arg, " \t") {
+				key, value, _ := strings.Cut(arg, "=")
+				args[i] = key + "=" + strconv.Quote(value)
+			}
+		}
+		fmt.Printf("%-*s  %s\n", width, name, strings.Join(args, " "))
+	}
+}
+
+// runViewCommand implements "github-feed view NAME [flags]", "view save" and "view list"
+func runViewCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: github-feed view NAME [flags] | view save NAME [flags] | view list")
+	}
+	if args[0] == "save" {
+		if len(args) < 2 {
+			return errors.New("usage: github-feed view save NAME [flags]")
+		}
+		return saveView(args[1], args[2:])
+	}
+
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+	if args[0] == "list" {
+		listViews(s.File.Views)
+		return nil
+	}
+
+	view, ok := s.File.Views[args[0]]
+	if !ok {
+		names := make([]string, 0, len(s.File.Views))
+		for name := range s.File.Views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown view %q (no views defined in %s)", args[0], s.Path)
+		}
+		return fmt.Errorf("unknown view %q (available: %s)", args[0], strings.Join(names, ", "))
+	}
+
+	// Flags after the view name override the view's own
+	runFeed(append(viewArgs(view), args[1:]...))
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestViewArgs(t *testing.T) {
+	view := map[string]configValue{"time": "1w", "label": "Review Requested,Reviewed", "links": "true"}
+	want := []string{"--label=Review Requested,Reviewed", "--links=true", "--time=1w"}
+	if got := viewArgs(view); !reflect.DeepEqual(got, want) {
+		t.Errorf("viewArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfigFileViewAliases(t *testing.T) {
+	dir := writeConfigFiles(t, `views:
+  review:
+    time: "1w"
+    labels: ["Review Requested", "Reviewed"]
+    repos: ["minio/*"]
+    group_by: "repo"
+`, "")
+	cfg, err := loadConfigFile(filepath.Join(dir, configFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]configValue{
+		"time":          "1w",
+		"label":         "Review Requested,Reviewed",
+		"allowed-repos": "minio/*",
+		"group-by":      "repo",
+	}
+	if got := cfg.Views["review"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("views.review = %v, want %v", got, want)
+	}
+}
+
+func TestSaveViewToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+	if err := os.WriteFile(path, []byte("# my settings\ntime: 2w # default window\nviews:\n  old:\n    time: 1d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := []viewOption{
+		{key: "allowed-repos", value: "*/docs,minio/minio"},
+		{key: "links", value: "true", bool: true},
+		{key: "time", value: "1w"},
+	}
+	replaced, err := saveViewToFile(path, "docs", options)
+	if err != nil || replaced {
+		t.Fatalf("saveViewToFile() = %v, %v, want a new view", replaced, err)
+	}
+	if replaced, err := saveViewToFile(path, "old", []viewOption{{key: "time", value: "3d"}}); err != nil || !replaced {
+		t.Fatalf("saveViewToFile(old) = %v, %v, want it replaced", replaced, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# my settings", "# default window"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved file lost comment %q:\n%s", want, data)
+		}
+	}
+
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Views["docs"]; got["allowed-repos"] != "*/docs,minio/minio" || got["links"] != "true" || got["time"] != "1w" {
+		t.Errorf("views.docs = %v", got)
+	}
+	if got := cfg.Views["old"]; len(got) != 1 || got["time"] != "3d" {
+		t.Errorf("views.old = %v, want only time: 3d", got)
+	}
+	if cfg.Options["time"] != "2w" {
+		t.Errorf("time = %q, want the top-level option kept", cfg.Options["time"])
+	}
+}
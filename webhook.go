@@ -0,0 +1,557 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+// updatesBucket maps item keys to the time a webhook event marked them as
+// updated. The marks are shown as ● by the next feed and then cleared.
+var updatesBucket = []byte("updates")
+
+// maxWebhookPayload is the largest payload accepted, GitHub caps them at 25 MB
+const maxWebhookPayload = 25 << 20
+
+// MarkUpdated records that the items changed outside a fetch cycle
+func (d *Database) MarkUpdated(keys []string, at time.Time) error {
+	value := []byte(at.UTC().Format(time.RFC3339))
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(updatesBucket)
+		for _, key := range keys {
+			if err := b.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateMarks returns the items marked by MarkUpdated and when
+func (d *Database) UpdateMarks() (map[string]time.Time, error) {
+	marks := make(map[string]time.Time)
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(updatesBucket)
+		if b == nil {
+			// A read-only snapshot of a database from before webhooks
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			at, _ := time.Parse(time.RFC3339, string(v))
+			marks[string(k)] = at
+			return nil
+		})
+	})
+	return marks, err
+}
+
+// ClearUpdateMarks removes the marks once the feed has shown them. Read-only
+// instances leave them for the next run that owns the database.
+func (d *Database) ClearUpdateMarks(keys []string) error {
+	if d.readOnly || len(keys) == 0 {
+		return nil
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(updatesBucket)
+		for _, key := range keys {
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// applyUpdateMarks sets HasUpdates on items a webhook marked, since their
+// cached updated_at already matches GitHub's. It returns the marked keys.
+func applyUpdateMarks(db *Database, activities []PRActivity, issues []IssueActivity) []string {
+	if db == nil {
+		return nil
+	}
+	marks, err := db.UpdateMarks()
+	if err != nil || len(marks) == 0 {
+		if err != nil && config.debugMode {
+			fmt.Printf("  [DB] Warning: Could not read update marks: %v\n", err)
+		}
+		return nil
+	}
+
+	var applied []string
+	for i := range activities {
+		key := buildItemKey(activities[i].Owner, activities[i].Repo, activities[i].PR.GetNumber())
+		if _, ok := marks[key]; ok {
+			activities[i].HasUpdates = true
+			applied = append(applied, key)
+		}
+	}
+	for i := range issues {
+		key := buildItemKey(issues[i].Owner, issues[i].Repo, issues[i].Issue.GetNumber())
+		if _, ok := marks[key]; ok {
+			issues[i].HasUpdates = true
+			applied = append(applied, key)
+		}
+	}
+	return applied
+}
+
+// verifyWebhookSignature checks an X-Hub-Signature-256 header against the payload
+func verifyWebhookSignature(secret, signature string, payload []byte) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// mentionsUser reports whether text @-mentions login
+func mentionsUser(text, login string) bool {
+	if login == "" {
+		return false
+	}
+	lower := strings.ToLower(text)
+	mention := "@" + strings.ToLower(login)
+	for i := strings.Index(lower, mention); i >= 0; {
+		end := i + len(mention)
+		if end == len(lower) || !isLoginChar(lower[end]) {
+			return true
+		}
+		next := strings.Index(lower[end:], mention)
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+	return false
+}
+
+func isLoginChar(c byte) bool {
+	return c == '-' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+func hasUser(users []*github.User, login string) bool {
+	for _, u := range users {
+		if strings.EqualFold(u.GetLogin(), login) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookChange is what one event changes in the cache
+type webhookChange struct {
+	owner, repo string
+	number      int
+	isPR        bool
+	pr          *github.PullRequest
+	issue       *github.Issue
+	reasons     []string // feed labels the event gives the configured user
+	texts       []string // bodies to look for cross-references in
+	prComment   *github.PullRequestComment
+	comment     *github.IssueComment
+	label       string // set by applyWebhookChange
+}
+
+// webhookItemLabel picks the feed label for the item, keeping a cached
+// label unless the event gives a higher-priority one
+func webhookItemLabel(cached string, reasons []string, isPR bool) string {
+	label := cached
+	for _, reason := range reasons {
+		if shouldUpdateLabel(label, reason, isPR) {
+			label = reason
+		}
+	}
+	return label
+}
+
+// parseWebhookChange turns a webhook event into a cache change. It returns nil
+// for events the feed doesn't use.
+func parseWebhookChange(eventType string, payload []byte, username string) (*webhookChange, error) {
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var c webhookChange
+	switch e := event.(type) {
+	case *github.PullRequestEvent:
+		pr := e.GetPullRequest()
+		c = webhookChange{isPR: true, pr: pr, number: pr.GetNumber(), texts: []string{pr.GetBody()}}
+		c.owner, c.repo = e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()
+		if strings.EqualFold(pr.GetUser().GetLogin(), username) {
+			c.reasons = append(c.reasons, "Authored")
+		}
+		if hasUser(pr.Assignees, username) {
+			c.reasons = append(c.reasons, "Assigned")
+		}
+		if hasUser(pr.RequestedReviewers, username) {
+			c.reasons = append(c.reasons, "Review Requested")
+		}
+		if mentionsUser(pr.GetBody(), username) {
+			c.reasons = append(c.reasons, "Mentioned")
+		}
+
+	case *github.IssuesEvent:
+		issue := e.GetIssue()
+		c = webhookChange{issue: issue, number: issue.GetNumber(), texts: []string{issue.GetBody()}}
+		c.owner, c.repo = e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()
+		if strings.EqualFold(issue.GetUser().GetLogin(), username) {
+			c.reasons = append(c.reasons, "Authored")
+		}
+		if hasUser(issue.Assignees, username) {
+			c.reasons = append(c.reasons, "Assigned")
+		}
+		if mentionsUser(issue.GetBody(), username) {
+			c.reasons = append(c.reasons, "Mentioned")
+		}
+
+	case *github.IssueCommentEvent:
+		issue, comment := e.GetIssue(), e.GetComment()
+		c = webhookChange{issue: issue, comment: comment, number: issue.GetNumber(), texts: []string{comment.GetBody()}}
+		c.owner, c.repo = e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()
+		if issue.IsPullRequest() {
+			c.isPR, c.pr, c.issue = true, prFromIssue(issue), nil
+		}
+		if strings.EqualFold(comment.GetUser().GetLogin(), username) {
+			c.reasons = append(c.reasons, "Commented")
+		}
+		if mentionsUser(comment.GetBody(), username) {
+			c.reasons = append(c.reasons, "Mentioned")
+		}
+
+	case *github.PullRequestReviewEvent:
+		pr, review := e.GetPullRequest(), e.GetReview()
+		c = webhookChange{isPR: true, pr: pr, number: pr.GetNumber(), texts: []string{review.GetBody()}}
+		c.owner, c.repo = e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()
+		if strings.EqualFold(review.GetUser().GetLogin(), username) {
+			c.reasons = append(c.reasons, "Reviewed")
+		}
+		if mentionsUser(review.GetBody(), username) {
+			c.reasons = append(c.reasons, "Mentioned")
+		}
+
+	case *github.PullRequestReviewCommentEvent:
+		pr, comment := e.GetPullRequest(), e.GetComment()
+		c = webhookChange{isPR: true, pr: pr, prComment: comment, number: pr.GetNumber(), texts: []string{comment.GetBody()}}
+		c.owner, c.repo = e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()
+		if strings.EqualFold(comment.GetUser().GetLogin(), username) {
+			c.reasons = append(c.reasons, "Commented")
+		}
+		if mentionsUser(comment.GetBody(), username) {
+			c.reasons = append(c.reasons, "Mentioned")
+		}
+
+	default:
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// crossReferences returns the cached items in the same repo that the change
+// mentions or that mention it
+func crossReferences(db *Database, c *webhookChange) ([]string, error) {
+	repos := []string{c.owner + "/" + c.repo}
+	prs, _, err := db.GetPullRequestsWithLabelsSince(time.Time{}, repos, config.debugMode)
+	if err != nil {
+		return nil, err
+	}
+	issues, _, err := db.GetIssuesWithLabelsSince(time.Time{}, repos, config.debugMode)
+	if err != nil {
+		return nil, err
+	}
+
+	self := buildItemKey(c.owner, c.repo, c.number)
+	var linked []string
+	check := func(key string, number int, body string) {
+		if key == self {
+			return
+		}
+		if mentionsNumber(body, c.number, c.owner, c.repo) {
+			linked = append(linked, key)
+			return
+		}
+		for _, text := range c.texts {
+			if mentionsNumber(text, number, c.owner, c.repo) {
+				linked = append(linked, key)
+				return
+			}
+		}
+	}
+	// PRs link to issues and issues to PRs, as in the feed
+	if c.isPR {
+		for key, issue := range issues {
+			check(key, issue.GetNumber(), issue.GetBody())
+		}
+	} else {
+		for key, pr := range prs {
+			check(key, pr.GetNumber(), pr.GetBody())
+		}
+	}
+	return linked, nil
+}
+
+// itemLocks serializes work on the same item key, dropping locks nobody waits on
+type itemLocks struct {
+	mu    sync.Mutex
+	locks map[string]*itemLock
+}
+
+type itemLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock locks key and returns the function that unlocks it
+func (l *itemLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*itemLock)
+	}
+	lock := l.locks[key]
+	if lock == nil {
+		lock = &itemLock{}
+		l.locks[key] = lock
+	}
+	lock.users++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		if lock.users--; lock.users == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// webhookItemLocks keeps concurrent deliveries for one item from overwriting
+// each other's labels
+var webhookItemLocks itemLocks
+
+// applyWebhookChange saves the change and marks the item and its cross-references
+// as updated. It returns the marked keys, or none when the item is not in the
+// feed for the configured user.
+func applyWebhookChange(db *Database, c *webhookChange) ([]string, error) {
+	if !isRepoAllowed(c.owner, c.repo) {
+		return nil, nil
+	}
+
+	// The cached label is read, raised and saved as one step per item
+	unlock := webhookItemLocks.lock(buildItemKey(c.owner, c.repo, c.number))
+	defer unlock()
+
+	var cached string
+	if c.isPR {
+		if old, label, err := db.GetPullRequestWithLabel(c.owner, c.repo, c.number); err == nil {
+			cached = label
+			// Comment events carry a partial PR; keep what the cache knows
+			if c.pr.Merged == nil {
+				c.pr.Merged, c.pr.MergedAt = old.Merged, old.MergedAt
+			}
+		}
+	} else if _, label, err := db.GetIssueWithLabel(c.owner, c.repo, c.number); err == nil {
+		cached = label
+	}
+	label := webhookItemLabel(cached, c.reasons, c.isPR)
+	if label == "" {
+		return nil, nil
+	}
+	c.label = label
+
+	var err error
+	if c.isPR {
+		err = db.SavePullRequestWithLabel(c.owner, c.repo, c.pr, label, config.debugMode)
+	} else {
+		err = db.SaveIssueWithLabel(c.owner, c.repo, c.issue, label, config.debugMode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.prComment != nil {
+		if err := db.SavePRComment(c.owner, c.repo, c.number, c.prComment, config.debugMode); err != nil {
+			return nil, err
+		}
+	}
+	if c.comment != nil {
+		if err := db.SaveComment(c.owner, c.repo, c.number, c.comment, "issue_comment"); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []string{buildItemKey(c.owner, c.repo, c.number)}
+	linked, err := crossReferences(db, c)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, linked...)
+	return keys, db.MarkUpdated(keys, time.Now())
+}
+
+// hookQueueSize bounds the deliveries waiting for their hooks to run
+const hookQueueSize = 100
+
+// startHookWorker runs hooks for webhook deliveries in the background, so
+// deliveries are answered before GitHub's 10s timeout. A single worker checks
+// and records fired hooks one delivery at a time. The returned channel is
+// closed once the worker drained the queue after ctx is done; the queue is nil
+// without hooks.
+func startHookWorker(ctx context.Context, db *Database, hooks []Hook) (chan<- hookEvent, <-chan struct{}) {
+	done := make(chan struct{})
+	if len(hooks) == 0 {
+		close(done)
+		return nil, done
+	}
+	queue := make(chan hookEvent, hookQueueSize)
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case e := <-queue:
+				runHooks(db, hooks, []hookEvent{e})
+			case <-ctx.Done():
+				for {
+					select {
+					case e := <-queue:
+						runHooks(db, hooks, []hookEvent{e})
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return queue, done
+}
+
+// webhookHandler receives GitHub webhook deliveries and applies them to db.
+// Hook events for updated items are sent to hookQueue when it is not nil.
+func webhookHandler(db *Database, secret string, hookQueue chan<- hookEvent) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayload))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !verifyWebhookSignature(secret, r.Header.Get("X-Hub-Signature-256"), payload) {
+			http.Error(w, "invalid X-Hub-Signature-256", http.StatusUnauthorized)
+			return
+		}
+
+		eventType := github.WebHookType(r)
+		if eventType == "ping" {
+			fmt.Fprintln(w, "pong")
+			return
+		}
+		change, err := parseWebhookChange(eventType, payload, config.username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if change == nil {
+			fmt.Fprintf(w, "ignored %s event\n", eventType)
+			return
+		}
+
+		keys, err := applyWebhookChange(db, change)
+		if err != nil {
+			fmt.Fprintf(config.statusOut, "Error: %s event for %s/%s#%d: %v\n", eventType, change.owner, change.repo, change.number, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(keys) == 0 {
+			fmt.Fprintf(w, "ignored %s/%s#%d (not in your feed)\n", change.owner, change.repo, change.number)
+			return
+		}
+		fmt.Fprintf(config.statusOut, "[%s] %s: updated %s\n", time.Now().Format("15:04:05"), eventType, strings.Join(keys, ", "))
+		if hookQueue != nil {
+			select {
+			case hookQueue <- webhookHookEvent(change):
+			default:
+				fmt.Fprintf(config.statusOut, "Warning: hook queue full, skipping hooks for %s\n", keys[0])
+			}
+		}
+		fmt.Fprintf(w, "updated %s\n", strings.Join(keys, ", "))
+	})
+}
+
+// runWebhookCommand implements "github-feed webhook"
+func runWebhookCommand(args []string) error {
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+	if config.hooks, err = compileHooks(s.File.Hooks); err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("webhook", flag.ExitOnError)
+	listen := fs.String("listen", ":9000", "Address to receive webhooks on")
+	secret := fs.String("secret", settingValue("webhook-secret"), "Webhook secret (default from GITHUB_WEBHOOK_SECRET)")
+	path := fs.String("path", "/", "URL path to receive webhooks on")
+	debug := fs.Bool("debug", false, "Show detailed logging output")
+	fs.Parse(args)
+
+	if *secret == "" {
+		return errors.New("a webhook secret is required (--secret or GITHUB_WEBHOOK_SECRET)")
+	}
+	config.debugMode = *debug
+	// Deliveries are logged to stderr, as serve logs its fetches
+	config.statusOut = os.Stderr
+	config.username = settingValue("username")
+	if config.username == "" {
+		return errors.New("GITHUB_USERNAME is required to decide which events belong in your feed")
+	}
+	config.repoFilter = newRepoFilter(settingValue("allowed-repos"), settingValue("exclude-repos"), settingValue("org"))
+
+	db, err := openCacheDatabase()
+	if err != nil {
+		return err
+	}
+	db.holdLock()
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	hookQueue, hooksDone := startHookWorker(ctx, db, config.hooks)
+	mux := http.NewServeMux()
+	mux.Handle(*path, webhookHandler(db, *secret, hookQueue))
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Receiving GitHub webhooks on http://%s%s\n", displayAddr(*listen), *path)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-hooksDone
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func signPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"zen":"Keep it logically awesome."}`)
+	if !verifyWebhookSignature("s3cret", signPayload("s3cret", string(payload)), payload) {
+		t.Error("valid signature rejected")
+	}
+	for _, signature := range []string{"", signPayload("other", string(payload)), "sha1=abc", "sha256=zz"} {
+		if verifyWebhookSignature("s3cret", signature, payload) {
+			t.Errorf("signature %q accepted", signature)
+		}
+	}
+}
+
+func TestMentionsUser(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"cc @alice", true},
+		{"@Alice: please look", true},
+		{"cc @alice-bot", false},
+		{"mail alice@example.com", false},
+		{"@alice2 and then @alice.", true},
+	}
+	for _, tt := range tests {
+		if got := mentionsUser(tt.text, "alice"); got != tt.want {
+			t.Errorf("mentionsUser(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+// Trimmed recorded deliveries
+const (
+	prOpenedPayload = `{
+  "action": "opened",
+  "pull_request": {"number": 5, "title": "Fix crash", "state": "open", "body": "Fixes the crash",
+    "html_url": "https://github.com/acme/app/pull/5", "updated_at": "2026-10-18T10:00:00Z",
+    "user": {"login": "bob"}, "requested_reviewers": [{"login": "alice"}]},
+  "repository": {"name": "app", "owner": {"login": "acme"}}
+}`
+	otherPRPayload = `{
+  "action": "opened",
+  "pull_request": {"number": 6, "title": "Unrelated", "state": "open", "updated_at": "2026-10-18T10:00:00Z",
+    "user": {"login": "carol"}},
+  "repository": {"name": "app", "owner": {"login": "acme"}}
+}`
+	reviewCommentPayload = `{
+  "action": "created",
+  "comment": {"id": 77, "body": "nit: rename this", "user": {"login": "alice"}},
+  "pull_request": {"number": 5, "title": "Fix crash", "state": "open", "body": "Fixes the crash",
+    "updated_at": "2026-10-18T11:00:00Z", "user": {"login": "bob"}},
+  "repository": {"name": "app", "owner": {"login": "acme"}}
+}`
+)
+
+func TestWebhookHandler(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	savedUser, savedFilter := config.username, config.repoFilter
+	defer func() { config.username, config.repoFilter = savedUser, savedFilter }()
+	config.username = "alice"
+	config.repoFilter = RepoFilter{}
+
+	// A cached issue that refers to the PR before the PR arrives
+	issue := &github.Issue{Number: github.Int(4), Title: github.String("Crash"), State: github.String("open"),
+		Body: github.String("Tracked in #5"), UpdatedAt: &github.Timestamp{Time: time.Now()}}
+	if err := db.SaveIssueWithLabel("acme", "app", issue, "Authored", false); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(webhookHandler(db, "s3cret", nil))
+	defer server.Close()
+
+	post := func(event, payload, signature string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(payload))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := post("pull_request", prOpenedPayload, signPayload("wrong", prOpenedPayload)); status != http.StatusUnauthorized {
+		t.Errorf("bad signature status = %d, want 401", status)
+	}
+	if status, body := post("ping", `{}`, signPayload("s3cret", `{}`)); status != http.StatusOK || !strings.Contains(body, "pong") {
+		t.Errorf("ping = %d %q, want 200 pong", status, body)
+	}
+
+	status, body := post("pull_request", prOpenedPayload, signPayload("s3cret", prOpenedPayload))
+	if status != http.StatusOK || !strings.Contains(body, "acme/app#5, acme/app#4") {
+		t.Fatalf("pull_request = %d %q, want the PR and its linked issue updated", status, body)
+	}
+	if _, label, err := db.GetPullRequestWithLabel("acme", "app", 5); err != nil || label != "Review Requested" {
+		t.Errorf("cached PR label = %q, %v, want Review Requested", label, err)
+	}
+
+	if _, body := post("pull_request", otherPRPayload, signPayload("s3cret", otherPRPayload)); !strings.Contains(body, "ignored") {
+		t.Errorf("unrelated PR response = %q, want ignored", body)
+	}
+	if _, _, err := db.GetPullRequestWithLabel("acme", "app", 6); err == nil {
+		t.Error("unrelated PR was cached")
+	}
+
+	// A comment by the user keeps the higher-priority label and is cached
+	if status, _ := post("pull_request_review_comment", reviewCommentPayload, signPayload("s3cret", reviewCommentPayload)); status != http.StatusOK {
+		t.Fatalf("pull_request_review_comment status = %d", status)
+	}
+	if _, label, _ := db.GetPullRequestWithLabel("acme", "app", 5); label != "Review Requested" {
+		t.Errorf("label after comment = %q, want Review Requested", label)
+	}
+	if comments, _ := db.GetPRComments("acme", "app", 5); len(comments) != 1 {
+		t.Errorf("cached %d review comments, want 1", len(comments))
+	}
+
+	marks, err := db.UpdateMarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range marks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "acme/app#4,acme/app#5" {
+		t.Errorf("update marks = %v, want acme/app#4 and acme/app#5", keys)
+	}
+
+	activities := []PRActivity{{Owner: "acme", Repo: "app", PR: &github.PullRequest{Number: github.Int(5)}}}
+	applied := applyUpdateMarks(db, activities, nil)
+	if !activities[0].HasUpdates || len(applied) != 1 {
+		t.Errorf("applyUpdateMarks() = %v, HasUpdates %v, want the PR marked", applied, activities[0].HasUpdates)
+	}
+	if err := db.ClearUpdateMarks(applied); err != nil {
+		t.Fatal(err)
+	}
+	if marks, _ := db.UpdateMarks(); len(marks) != 1 {
+		t.Errorf("%d marks left after clearing the PR, want 1", len(marks))
+	}
+}
+
+func TestWebhookHooksRunAfterResponse(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	savedUser, savedFilter, savedOut := config.username, config.repoFilter, config.statusOut
+	defer func() { config.username, config.repoFilter, config.statusOut = savedUser, savedFilter, savedOut }()
+	config.username = "alice"
+	config.repoFilter = RepoFilter{}
+	config.statusOut = io.Discard
+
+	// The chat webhook blocks until the delivery has been answered
+	release := make(chan struct{})
+	chat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer chat.Close()
+	hooks, err := compileHooks([]hookConfig{{Name: "chat", When: "has_updates", Webhook: chat.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue, done := startHookWorker(ctx, db, hooks)
+	server := httptest.NewServer(webhookHandler(db, "s3cret", queue))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(prOpenedPayload))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-Hub-Signature-256", signPayload("s3cret", prOpenedPayload))
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("delivery was not answered while its hook ran: %v", err)
+	}
+	resp.Body.Close()
+
+	close(release)
+	cancel()
+	<-done
+	pr, _, err := db.GetPullRequestWithLabel("acme", "app", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := webhookHookEvent(&webhookChange{isPR: true, owner: "acme", repo: "app", label: "Review Requested", pr: pr})
+	if fired, err := db.HookFired("chat", event); err != nil || !fired {
+		t.Errorf("HookFired() after the worker drained = %v, %v, want true", fired, err)
+	}
+}
+
+func TestApplyWebhookChangeConcurrent(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	savedFilter := config.repoFilter
+	defer func() { config.repoFilter = savedFilter }()
+	config.repoFilter = RepoFilter{}
+
+	// Review requests and comments racing on one PR must end with the higher label
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		reason := "Commented"
+		if i%2 == 0 {
+			reason = "Review Requested"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pr := &github.PullRequest{Number: github.Int(5), UpdatedAt: &github.Timestamp{Time: time.Now()}}
+			change := &webhookChange{isPR: true, pr: pr, owner: "acme", repo: "app", number: 5, reasons: []string{reason}}
+			if _, err := applyWebhookChange(db, change); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, label, err := db.GetPullRequestWithLabel("acme", "app", 5); err != nil || label != "Review Requested" {
+		t.Errorf("label after concurrent deliveries = %q, %v, want Review Requested", label, err)
+	}
+	if len(webhookItemLocks.locks) != 0 {
+		t.Errorf("%d item locks left after the deliveries", len(webhookItemLocks.locks))
+	}
+}